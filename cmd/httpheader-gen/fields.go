@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"net/textproto"
+	"reflect"
+	"strconv"
+
+	"github.com/mozillazg/go-httpheader/internal/fieldtag"
+)
+
+// fieldKind selects how a struct field is encoded or decoded, as in the
+// httpheader package.
+type fieldKind = fieldtag.Kind
+
+const (
+	kindValue   = fieldtag.KindValue
+	kindSlice   = fieldtag.KindSlice
+	kindMulti   = fieldtag.KindMulti
+	kindPtr     = fieldtag.KindPtr
+	kindStruct  = fieldtag.KindStruct
+	kindHeader  = fieldtag.KindHeader
+	kindPrefix  = fieldtag.KindPrefix
+	kindEncoder = fieldtag.KindEncoder
+	kindDecoder = fieldtag.KindDecoder
+)
+
+// tagOptions are the options of a struct field's "header" tag.
+type tagOptions = fieldtag.Options
+
+// field is a struct field, or a field of a nested or embedded struct, that
+// is encoded to or decoded from a Header field. The fields of a struct are
+// listed in the same order as by the httpheader package.
+type field struct {
+	name      string // Header field name as given by the tag or struct field
+	key       string // canonical format of name, or name if raw
+	path      string // path of the struct field, e.g. "Outer.Inner.Retry"
+	expr      string // Go expression of the struct field
+	typ       *goType
+	opts      tagOptions
+	omitEmpty bool
+	required  bool
+	defaults  []string
+	sep       string
+	raw       bool // Header field names are used verbatim
+	kind      fieldKind
+	fields    []field // fields of kindStruct fields
+}
+
+// kindFunc chooses how a struct field is handled, see typeFields.
+type kindFunc func(t *goType, opts tagOptions, exported bool) (fieldKind, bool)
+
+// typeFields returns the fields of the struct type t, accessed as expr and
+// found at path, in the order they are encoded or decoded. It follows the
+// typeFields function of the httpheader package.
+func (p *packageInfo) typeFields(t *goType, path, expr string, kindOf kindFunc) ([]field, error) {
+	var fields, embedded []field
+
+	for _, sf := range t.st.Fields.List {
+		ft := p.resolve(sf.Type, t.file)
+		tag := ""
+		if sf.Tag != nil {
+			s, _ := strconv.Unquote(sf.Tag.Value)
+			tag = reflect.StructTag(s).Get("header")
+		}
+
+		names := sf.Names
+		anonymous := len(names) == 0
+		if anonymous {
+			names = []*ast.Ident{embeddedName(sf.Type)}
+		}
+		for _, id := range names {
+			exported := ast.IsExported(id.Name)
+			if !exported && !anonymous {
+				continue
+			}
+			if tag == "-" {
+				continue
+			}
+			fpath := joinPath(path, id.Name)
+			fexpr := expr + "." + id.Name
+
+			name, opts := fieldtag.Parse(tag)
+			if name == "" {
+				if anonymous && ft.kind == kStruct {
+					// save embedded struct for later processing
+					fs, err := p.typeFields(ft, fpath, fexpr, kindOf)
+					if err != nil {
+						return nil, err
+					}
+					embedded = append(embedded, fs...)
+					continue
+				}
+				if !exported && indirect(ft).kind != kStruct {
+					// unexported non-struct embedded fields cannot be accessed
+					continue
+				}
+				name = id.Name
+			}
+
+			if ft.kind == kUnsupported {
+				return nil, fmt.Errorf("%s: %s", fpath, ft.why)
+			}
+			if _, err := fieldtag.Dup(opts); err != nil {
+				return nil, fmt.Errorf("%s: %v", fpath, err)
+			}
+			kind, flatten := kindOf(ft, opts, exported)
+			if flatten {
+				fs, err := p.typeFields(ft, fpath, fexpr, kindOf)
+				if err != nil {
+					return nil, err
+				}
+				fields = append(fields, fs...)
+				continue
+			}
+
+			f := field{
+				name:      name,
+				key:       textproto.CanonicalMIMEHeaderKey(name),
+				path:      fpath,
+				expr:      fexpr,
+				typ:       ft,
+				opts:      opts,
+				omitEmpty: opts.Contains("omitempty") && kind != kindPrefix && kind != kindHeader,
+				required:  opts.Contains("required"),
+				sep:       fieldtag.ListSeparator(opts),
+				raw:       opts.Contains("raw"),
+				kind:      kind,
+			}
+			if f.raw {
+				f.key = name
+			}
+			f.defaults = defaultValues(ft, kind, opts, f.sep)
+			if kind == kindStruct {
+				if ft.kind != kStruct {
+					return nil, fmt.Errorf("%s: unsupported pointer to struct", fpath)
+				}
+				fs, err := p.typeFields(ft, fpath, fexpr, kindOf)
+				if err != nil {
+					return nil, err
+				}
+				f.fields = fs
+			}
+			fields = append(fields, f)
+		}
+	}
+
+	return append(fields, embedded...), nil
+}
+
+// embeddedName returns the name of the embedded field of type expr.
+func embeddedName(expr ast.Expr) *ast.Ident {
+	switch x := expr.(type) {
+	case *ast.StarExpr:
+		return embeddedName(x.X)
+	case *ast.SelectorExpr:
+		return x.Sel
+	case *ast.Ident:
+		return x
+	}
+	return ast.NewIdent("")
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// encodeKind is the kindFunc of the EncodeHeader method, see the encodeKind
+// method of httpheader.HeaderEncoder.
+func (p *packageInfo) encodeKind(t *goType, opts tagOptions, exported bool) (fieldKind, bool) {
+	if !p.isGenerated(t) {
+		if p.hasValueMethod(t, "EncodeHeader") || (t.kind == kPtr && p.hasMethod(t.elem, "EncodeHeader")) {
+			return kindEncoder, false
+		}
+	}
+	if opts.Contains("prefix") && isPrefixMap(t) {
+		return kindPrefix, false
+	}
+	base := indirect(t)
+	if base.kind == kTime || p.hasMethod(base, "MarshalText") {
+		return kindValue, false
+	}
+	if t.kind == kSlice {
+		return kindSlice, false
+	}
+
+	switch base.kind {
+	case kHeader:
+		return kindHeader, false
+	case kStruct:
+		return kindStruct, base == t
+	}
+	return kindValue, false
+}
+
+// decodeKind is the kindFunc of the DecodeHeader method, see the decodeKind
+// method of httpheader.HeaderDecoder.
+func (p *packageInfo) decodeKind(t *goType, opts tagOptions, exported bool) (fieldKind, bool) {
+	if exported && !p.isGenerated(t) {
+		if t.kind != kPtr && p.hasMethod(t, "DecodeHeader") {
+			return kindDecoder, false
+		}
+		if t.kind == kPtr && p.hasMethod(t.elem, "DecodeHeader") {
+			return kindDecoder, false
+		}
+	}
+	if opts.Contains("prefix") && isPrefixMap(t) {
+		return kindPrefix, false
+	}
+	if t.kind != kTime && t.kind != kPtr && p.hasMethod(t, "UnmarshalText") {
+		return kindValue, false
+	}
+	if t.kind == kHeader {
+		return kindHeader, false
+	}
+
+	switch t.kind {
+	case kPtr:
+		return kindPtr, false
+	case kStruct:
+		// a struct with the omitempty option is only decoded when its
+		// Header field is present, so it can't be flattened.
+		return kindStruct, !opts.Contains("omitempty")
+	case kTime, kSlice:
+		return kindMulti, false
+	}
+	return kindValue, false
+}
+
+// defaultValues returns the values of the "default" option, see the
+// defaultValues method of the httpheader package.
+func defaultValues(t *goType, kind fieldKind, opts tagOptions, sep string) []string {
+	list := false
+	switch kind {
+	case kindSlice, kindMulti, kindPtr:
+		list = indirect(t).kind == kSlice
+	}
+	return fieldtag.Defaults(opts, list, sep, fieldtag.DefaultSeparator)
+}
@@ -0,0 +1,568 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+)
+
+// generator writes the methods of the struct types of a package.
+type generator struct {
+	*packageInfo
+	buf     bytes.Buffer
+	imports map[string]bool
+	tmp     int // counter of temporary variables
+
+	// Header fields decoded by the fields of the type being generated, see
+	// the knownHeaders method of httpheader.HeaderDecoder.
+	known, prefixes []string
+}
+
+// generate returns the source of the EncodeHeader and DecodeHeader methods
+// of the struct types named types in the package in dir.
+func generate(dir string, types []string) ([]byte, error) {
+	p, err := loadPackage(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range types {
+		if _, ok := p.specs[name]; !ok {
+			return nil, fmt.Errorf("type %s not found in %s", name, dir)
+		}
+		// the methods being generated are part of the package
+		p.addMethod(name, "EncodeHeader", false)
+		p.addMethod(name, "DecodeHeader", true)
+	}
+
+	g := &generator{
+		packageInfo: p,
+		imports: map[string]bool{
+			"net/http":                           true,
+			"github.com/mozillazg/go-httpheader": true,
+		},
+	}
+	var body bytes.Buffer
+	for _, name := range types {
+		if err := g.generateType(name); err != nil {
+			return nil, fmt.Errorf("%s.%v", name, err)
+		}
+		body.Write(g.buf.Bytes())
+		g.buf.Reset()
+	}
+
+	g.printf("// Code generated by httpheader-gen; DO NOT EDIT.\n\n")
+	g.printf("package %s\n\n", p.name)
+	g.printf("import (\n")
+	var std, other []string
+	for path := range g.imports {
+		if strings.Contains(path, ".") {
+			other = append(other, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	sort.Strings(std)
+	for _, path := range std {
+		g.printf("%q\n", path)
+	}
+	g.printf("\n")
+	for _, path := range other {
+		g.printf("%q\n", path)
+	}
+	g.printf(")\n")
+	g.buf.Write(body.Bytes())
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("invalid generated code: %v\n%s", err, g.buf.Bytes())
+	}
+	return src, nil
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// newVar returns the name of a new temporary variable.
+func (g *generator) newVar(prefix string) string {
+	g.tmp++
+	return fmt.Sprintf("%s%d", prefix, g.tmp)
+}
+
+// typeExpr returns the Go syntax of t, recording the imports it needs.
+func (g *generator) typeExpr(t *goType) string {
+	for u := t; u != nil; u = u.elem {
+		if u.name == "" && (u.kind == kTime || u.kind == kDuration) {
+			g.imports["time"] = true
+		}
+		if u.key != nil {
+			g.typeExpr(u.key)
+		}
+	}
+	return t.expr
+}
+
+func (g *generator) generateType(name string) error {
+	t := g.named(name)
+	if t.kind != kStruct {
+		return fmt.Errorf("%s is not a struct type", name)
+	}
+	g.tmp = 0
+
+	fields, err := g.typeFields(t, "", "s", g.encodeKind)
+	if err != nil {
+		return err
+	}
+	g.printf("\n// EncodeHeader implements the httpheader.Encoder interface. It encodes\n")
+	g.printf("// the fields of s into header as described in the documentation for\n")
+	g.printf("// httpheader.Header. key is ignored.\n")
+	g.printf("func (s %s) EncodeHeader(key string, header *http.Header) error {\n", name)
+	g.printf("if *header == nil {\n*header = make(http.Header)\n}\n")
+	g.printf("e := httpheader.GeneratedEncoder{Header: *header, Struct: %q}\n", name)
+	for i := range fields {
+		if err := g.encodeField(&fields[i]); err != nil {
+			return err
+		}
+	}
+	g.printf("return nil\n}\n")
+
+	fields, err = g.typeFields(t, "", "s", g.decodeKind)
+	if err != nil {
+		return err
+	}
+	g.known, g.prefixes = nil, nil
+	g.knownHeaders(fields)
+	g.printf("\n// DecodeHeader implements the httpheader.Decoder interface. It decodes\n")
+	g.printf("// header into the fields of s as described in the documentation for\n")
+	g.printf("// httpheader.Decode. key is ignored.\n")
+	g.printf("func (s *%s) DecodeHeader(header http.Header, key string) error {\n", name)
+	g.printf("d := httpheader.GeneratedDecoder{Header: header, Struct: %q}\n", name)
+	for i := range fields {
+		if err := g.decodeField(&fields[i]); err != nil {
+			return err
+		}
+	}
+	g.printf("return d.Err()\n}\n")
+
+	g.printf("\n// HTTPHeaderGenerated marks the methods of %s as generated by\n", name)
+	g.printf("// httpheader-gen, so that httpheader.Header and httpheader.Decode call them.\n")
+	g.printf("func (%s) HTTPHeaderGenerated() interface{} {\nreturn (*%s)(nil)\n}\n", name, name)
+	return nil
+}
+
+// unsupportedField returns the error reported for a field whose type is
+// not supported by the generated code.
+func unsupportedField(f *field) error {
+	return fmt.Errorf("%s: unsupported type %s", f.path, f.typ.expr)
+}
+
+// encodeField writes the code encoding f, see the reflectValue method of
+// httpheader.HeaderEncoder.
+func (g *generator) encodeField(f *field) error {
+	g.printf("// %s\n", f.path)
+	nonEmpty := ""
+	if f.omitEmpty {
+		nonEmpty = nonEmptyExpr(f.typ, f.expr)
+	}
+	if nonEmpty != "" && !(f.kind == kindSlice && f.sep != "") {
+		g.printf("if %s {\n", nonEmpty)
+	} else {
+		g.printf("{\n")
+	}
+
+	switch f.kind {
+	case kindEncoder:
+		if f.typ.kind == kPtr {
+			return unsupportedField(f)
+		}
+		g.printf("if err := e.Encode(%q, %q, %s); err != nil {\nreturn err\n}\n", f.path, f.name, f.expr)
+	case kindSlice:
+		v := g.newVar("v")
+		if f.sep == "" {
+			g.printf("for _, %s := range %s {\n", v, f.expr)
+			stmts, expr, err := g.format(f.typ.elem, v, f.opts)
+			if err != nil {
+				return fmt.Errorf("%s: %v", f.path, err)
+			}
+			g.printf("%s", stmts)
+			g.printf("if err := e.Add(%q, %q, %s); err != nil {\nreturn err\n}\n", f.path, f.key, expr)
+			g.printf("}\n")
+			break
+		}
+		vs := g.newVar("vs")
+		g.printf("if len(%s) > 0 {\n", f.expr)
+		g.printf("%s := make([]string, 0, len(%s))\n", vs, f.expr)
+		g.printf("for _, %s := range %s {\n", v, f.expr)
+		stmts, expr, err := g.format(f.typ.elem, v, f.opts)
+		if err != nil {
+			return fmt.Errorf("%s: %v", f.path, err)
+		}
+		g.printf("%s", stmts)
+		g.printf("%s = append(%s, %s)\n", vs, vs, expr)
+		g.printf("}\n")
+		g.printf("if err := e.AddList(%q, %q, %s, %q); err != nil {\nreturn err\n}\n", f.path, f.key, vs, f.sep)
+		g.printf("}\n")
+	case kindHeader:
+		if f.typ.kind != kHeader {
+			return unsupportedField(f)
+		}
+		g.printf("if err := e.AddHeader(%q, %s, %v); err != nil {\nreturn err\n}\n", f.path, f.expr, !f.raw)
+	case kindPrefix:
+		k, v := g.newVar("k"), g.newVar("v")
+		g.printf("for %s, %s := range %s {\n", k, v, f.expr)
+		key := fmt.Sprintf("%q + %s", f.name, conv("string", f.typ.key, k))
+		if !f.raw {
+			key = "http.CanonicalHeaderKey(" + key + ")"
+		}
+		if f.typ.elem.kind == kSlice {
+			s := g.newVar("s")
+			g.printf("for _, %s := range %s {\n", s, v)
+			g.printf("if err := e.Add(%q, %s, %s); err != nil {\nreturn err\n}\n", f.path, key, conv("string", f.typ.elem.elem, s))
+			g.printf("}\n")
+		} else {
+			g.printf("if err := e.Add(%q, %s, %s); err != nil {\nreturn err\n}\n", f.path, key, conv("string", f.typ.elem, v))
+		}
+		g.printf("}\n")
+	case kindValue:
+		stmts, expr, err := g.format(f.typ, f.expr, f.opts)
+		if err != nil {
+			return fmt.Errorf("%s: %v", f.path, err)
+		}
+		g.printf("%s", stmts)
+		g.printf("if err := e.Add(%q, %q, %s); err != nil {\nreturn err\n}\n", f.path, f.key, expr)
+	default:
+		return unsupportedField(f)
+	}
+	g.printf("}\n")
+	return nil
+}
+
+// nonEmptyExpr returns the expression reporting whether x, of type t, is
+// not empty, or "" if it never is, see the isEmptyValue function of the
+// httpheader package.
+func nonEmptyExpr(t *goType, x string) string {
+	switch t.kind {
+	case kString, kSlice, kMap, kHeader:
+		return "len(" + x + ") > 0"
+	case kBool:
+		return x
+	case kInt, kUint, kFloat, kDuration:
+		return x + " != 0"
+	case kPtr:
+		return x + " != nil"
+	case kTime:
+		return "!" + x + ".IsZero()"
+	}
+	return ""
+}
+
+// conv returns the expression converting x, of type t, to the basic type
+// typ if t is a named type.
+func conv(typ string, t *goType, x string) string {
+	if t.name == "" && t.expr == typ {
+		return x
+	}
+	return typ + "(" + x + ")"
+}
+
+// convFrom returns the expression converting x, of the basic type typ, to
+// t.
+func convFrom(typ string, t *goType, x string) string {
+	if t.expr == typ {
+		return x
+	}
+	return t.expr + "(" + x + ")"
+}
+
+func (g *generator) strconv() {
+	g.imports["strconv"] = true
+}
+
+// format returns the statements and the expression encoding x, of type t,
+// see the valueString method of httpheader.HeaderEncoder.
+func (g *generator) format(t *goType, x string, opts tagOptions) (string, string, error) {
+	switch {
+	case t.kind == kPtr:
+		if t.elem.kind == kPtr {
+			break
+		}
+		stmts, expr, err := g.format(t.elem, "(*"+x+")", opts)
+		if err != nil {
+			return "", "", err
+		}
+		v := g.newVar("v")
+		return fmt.Sprintf("%s := \"\"\nif %s != nil {\n%s%s = %s\n}\n", v, x, stmts, v, expr), v, nil
+	case t.kind == kBool && opts.Contains("int"):
+		v := g.newVar("v")
+		return fmt.Sprintf("%s := \"0\"\nif %s {\n%s = \"1\"\n}\n", v, x, v), v, nil
+	case t.kind == kTime:
+		return "", fmt.Sprintf("e.FormatTime(%s, %q)", x, opts.String()), nil
+	case t.kind == kDuration:
+		return "", fmt.Sprintf("e.FormatDuration(%s, %q)", x, opts.String()), nil
+	case g.hasMethod(t, "MarshalText"):
+		b := g.newVar("b")
+		return fmt.Sprintf("%s, err := %s.MarshalText()\nif err != nil {\nreturn err\n}\n", b, x), "string(" + b + ")", nil
+	case g.hasValueMethod(t, "Format") || g.hasValueMethod(t, "Error") || g.hasValueMethod(t, "String"):
+		g.imports["fmt"] = true
+		return "", "fmt.Sprint(" + x + ")", nil
+	case t.kind == kString:
+		return "", conv("string", t, x), nil
+	case t.kind == kBool:
+		g.strconv()
+		return "", "strconv.FormatBool(" + conv("bool", t, x) + ")", nil
+	case t.kind == kInt:
+		g.strconv()
+		return "", "strconv.FormatInt(" + conv("int64", t, x) + ", 10)", nil
+	case t.kind == kUint:
+		g.strconv()
+		return "", "strconv.FormatUint(" + conv("uint64", t, x) + ", 10)", nil
+	case t.kind == kFloat:
+		g.strconv()
+		return "", fmt.Sprintf("strconv.FormatFloat(%s, 'g', -1, %d)", conv("float64", t, x), t.bits), nil
+	}
+	return "", "", fmt.Errorf("unsupported type %s", t.expr)
+}
+
+// decodeField writes the code decoding f, see the parseValue method of the
+// httpheader package.
+func (g *generator) decodeField(f *field) error {
+	noop := f.kind == kindValue && f.typ.kind == kMap && !g.hasMethod(f.typ, "UnmarshalText")
+	if noop && !f.required {
+		return nil
+	}
+
+	g.printf("// %s\n", f.path)
+	if f.required {
+		g.printf("if !d.Present(%q, %v) {\n", f.key, f.kind == kindPrefix)
+		g.printf("d.Missing(%q, %q)\n", f.path, f.name)
+		if noop {
+			g.printf("}\n")
+			return nil
+		}
+		g.printf("} else {\n")
+	} else {
+		g.printf("{\n")
+	}
+
+	needVals := f.omitEmpty || (f.kind != kindDecoder && f.kind != kindPrefix && f.kind != kindHeader)
+	split := f.sep != "" && f.kind != kindValue && !g.singleValue(f)
+	dup := g.duplicates(f)
+	errVals := "vals"
+	if needVals {
+		g.printf("vals := header[%q]\n", f.key)
+		if f.defaults != nil {
+			if split {
+				g.printf("fromHeader := len(vals) > 0\n")
+			}
+			g.printf("if len(vals) == 0 {\nvals = %#v\n}\n", f.defaults)
+		}
+		if (split || dup) && (f.kind == kindPtr || f.kind == kindMulti) && g.parseFails(f.typ) {
+			g.printf("raw := vals\n")
+			errVals = "raw"
+		}
+		if split {
+			if f.defaults != nil {
+				g.printf("if fromHeader {\n")
+			}
+			g.printf("vals = d.SplitList(vals, %q)\n", f.sep)
+			if f.defaults != nil {
+				g.printf("}\n")
+			}
+		}
+		if dup {
+			g.printf("vals, err := d.Duplicates(%q, %q, vals, %q)\n", f.path, f.name, f.opts.String())
+			g.printf("if err != nil {\nreturn err\n}\n")
+		}
+	}
+
+	cond := "len(vals) > 0"
+	if f.omitEmpty {
+		cond = `len(vals) > 0 && vals[0] != ""`
+	}
+	errRet := func(vals string) string {
+		return fmt.Sprintf("return d.Error(%q, %q, %s, err)", f.path, f.name, vals)
+	}
+
+	switch f.kind {
+	case kindDecoder:
+		if f.typ.kind == kPtr {
+			return unsupportedField(f)
+		}
+		if f.omitEmpty {
+			g.printf("if %s {\n", cond)
+		}
+		g.printf("if err := %s.DecodeHeader(header, %q); err != nil {\n", f.expr, f.name)
+		g.printf("%s\n}\n", errRet(fmt.Sprintf("header[%q]", f.key)))
+		if f.omitEmpty {
+			g.printf("}\n")
+		}
+	case kindPrefix:
+		k, vs := g.newVar("k"), g.newVar("vs")
+		g.printf("d.Prefixed(%q, func(%s string, %s []string) {\n", f.key, k, vs)
+		g.printf("if %s == nil {\n%s = make(%s)\n}\n", f.expr, f.expr, g.typeExpr(f.typ))
+		key := conv(f.typ.key.expr, f.typ.key, k)
+		if elem := f.typ.elem; elem.kind == kSlice {
+			v, i, s := g.newVar("v"), g.newVar("i"), g.newVar("s")
+			g.printf("%s := make(%s, len(%s))\n", v, g.typeExpr(elem), vs)
+			g.printf("for %s, %s := range %s {\n%s[%s] = %s\n}\n", i, s, vs, v, i, conv(elem.elem.expr, elem.elem, s))
+			g.printf("%s[%s] = %s\n", f.expr, key, v)
+		} else {
+			g.printf("%s[%s] = %s\n", f.expr, key, conv(elem.expr, elem, vs+"[0]"))
+		}
+		g.printf("})\n")
+	case kindHeader:
+		if f.typ.kind != kHeader {
+			return unsupportedField(f)
+		}
+		rest := g.newVar("rest")
+		g.printf("if %s := d.Rest(%#v, %#v); %s != nil {\n", rest, g.known, g.prefixes, rest)
+		g.printf("%s = %s\n}\n", f.expr, rest)
+	case kindPtr:
+		g.printf("if %s {\n", cond)
+		p := g.newVar("p")
+		g.printf("%s := new(%s)\n", p, g.typeExpr(f.typ.elem))
+		if err := g.fill(f.typ.elem, "(*"+p+")", f.opts, errRet(errVals)); err != nil {
+			return fmt.Errorf("%s: %v", f.path, err)
+		}
+		g.printf("%s = %s\n", f.expr, p)
+		g.printf("}\n")
+	case kindMulti:
+		g.printf("if %s {\n", cond)
+		if err := g.fill(f.typ, f.expr, f.opts, errRet(errVals)); err != nil {
+			return fmt.Errorf("%s: %v", f.path, err)
+		}
+		g.printf("}\n")
+	case kindStruct:
+		g.printf("if %s {\n", cond)
+		for i := range f.fields {
+			if err := g.decodeField(&f.fields[i]); err != nil {
+				return err
+			}
+		}
+		g.printf("}\n")
+	case kindValue:
+		g.printf("if %s {\n", cond)
+		if err := g.parse(f.typ, f.expr, "vals[0]", f.opts, errRet("vals[:1]")); err != nil {
+			return fmt.Errorf("%s: %v", f.path, err)
+		}
+		g.printf("}\n")
+	default:
+		return unsupportedField(f)
+	}
+	g.printf("}\n")
+	return nil
+}
+
+// duplicates reports whether the "dup" option of f selects another policy
+// than "first" and applies to it.
+func (g *generator) duplicates(f *field) bool {
+	switch dup, _ := f.opts.Value("dup"); dup {
+	case "last", "join", "unique":
+		return g.singleValue(f)
+	}
+	return false
+}
+
+// singleValue reports whether f is decoded from a single value of its Header
+// field, see the singleValue method of httpheader.HeaderDecoder.
+func (g *generator) singleValue(f *field) bool {
+	switch f.kind {
+	case kindValue:
+		return true
+	case kindMulti:
+		return f.typ.kind == kTime
+	case kindPtr:
+		return f.typ.elem.kind != kSlice || g.hasMethod(f.typ.elem, "UnmarshalText")
+	}
+	return false
+}
+
+// knownHeaders adds the Header field names decoded by fields to g.known, and
+// the prefixes of its "prefix" fields to g.prefixes.
+func (g *generator) knownHeaders(fields []field) {
+	for i := range fields {
+		f := &fields[i]
+		switch f.kind {
+		case kindStruct:
+			if f.omitEmpty {
+				g.known = append(g.known, f.key)
+			}
+			g.knownHeaders(f.fields)
+		case kindHeader:
+		case kindPrefix:
+			g.prefixes = append(g.prefixes, f.key)
+		default:
+			g.known = append(g.known, f.key)
+		}
+	}
+}
+
+// fill writes the code decoding vals into x, of type t, see the fillValues
+// method of httpheader.HeaderDecoder. errRet returns err.
+func (g *generator) fill(t *goType, x string, opts tagOptions, errRet string) error {
+	if t.kind != kSlice || g.hasMethod(t, "UnmarshalText") {
+		return g.parse(t, x, "vals[0]", opts, errRet)
+	}
+	sl, i, v := g.newVar("sl"), g.newVar("i"), g.newVar("v")
+	g.printf("%s := make(%s, len(vals))\n", sl, g.typeExpr(t))
+	g.printf("for %s, %s := range vals {\n", i, v)
+	if err := g.parse(t.elem, sl+"["+i+"]", v, opts, errRet); err != nil {
+		return err
+	}
+	g.printf("}\n")
+	g.printf("%s = %s\n", x, sl)
+	return nil
+}
+
+// parseFails reports whether decoding a value of type t may fail.
+func (g *generator) parseFails(t *goType) bool {
+	switch {
+	case t.kind == kPtr:
+		return g.parseFails(t.elem)
+	case t.kind == kDuration || t.kind == kTime || g.hasMethod(t, "UnmarshalText"):
+		return true
+	case t.kind == kSlice:
+		return g.parseFails(t.elem)
+	}
+	return t.kind != kBool && t.kind != kString
+}
+
+// parse writes the code decoding the string v into x, of type t, see the
+// fillValues method of httpheader.HeaderDecoder. errRet returns err.
+func (g *generator) parse(t *goType, x, v string, opts tagOptions, errRet string) error {
+	switch {
+	case t.kind == kDuration:
+		n := g.newVar("n")
+		g.printf("%s, err := d.ParseDuration(%s, %q)\nif err != nil {\n%s\n}\n%s = %s\n", n, v, opts.String(), errRet, x, n)
+	case t.kind != kTime && g.hasMethod(t, "UnmarshalText"):
+		g.printf("if err := %s.UnmarshalText([]byte(%s)); err != nil {\n%s\n}\n", x, v, errRet)
+	case t.kind == kBool:
+		falseValue := "false"
+		if opts.Contains("int") {
+			falseValue = "0"
+		}
+		g.printf("%s = %s\n", x, conv(t.expr, t, fmt.Sprintf("%s != %q", v, falseValue)))
+	case t.kind == kString:
+		g.printf("%s = %s\n", x, conv(t.expr, t, v))
+	case t.kind == kInt:
+		g.strconv()
+		n := g.newVar("n")
+		g.printf("%s, err := strconv.ParseInt(%s, 10, %d)\nif err != nil {\n%s\n}\n%s = %s\n", n, v, t.bits, errRet, x, convFrom("int64", t, n))
+	case t.kind == kUint:
+		g.strconv()
+		n := g.newVar("n")
+		g.printf("%s, err := strconv.ParseUint(%s, 10, %d)\nif err != nil {\n%s\n}\n%s = %s\n", n, v, t.bits, errRet, x, convFrom("uint64", t, n))
+	case t.kind == kFloat:
+		g.strconv()
+		n := g.newVar("n")
+		g.printf("%s, err := strconv.ParseFloat(%s, %d)\nif err != nil {\n%s\n}\n%s = %s\n", n, v, t.bits, errRet, x, convFrom("float64", t, n))
+	case t.kind == kTime:
+		tm := g.newVar("t")
+		g.printf("%s, err := d.ParseTime(%s, %q)\nif err != nil {\n%s\n}\n%s = %s\n", tm, v, opts.String(), errRet, x, tm)
+	default:
+		return fmt.Errorf("unsupported type %s", t.expr)
+	}
+	return nil
+}
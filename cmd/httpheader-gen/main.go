@@ -0,0 +1,82 @@
+// Command httpheader-gen generates EncodeHeader and DecodeHeader methods for
+// structs, implementing the httpheader.Encoder and httpheader.Decoder
+// interfaces without reflection, and an HTTPHeaderGenerated method marking
+// them as generated.
+//
+// The generated methods encode and decode the struct fields with the same
+// rules as httpheader.Header and httpheader.Decode, which call them instead
+// of using reflection. Structs embedding a generated struct don't have their
+// own methods generated and are still handled by reflection. Fields of
+// generated struct types are handled like plain structs, by the generated
+// methods of the outer struct or by reflection. HeaderEncoder and
+// HeaderDecoder values created with NewEncoder and NewDecoder keep using
+// reflection, so that their options apply. Converters registered with
+// RegisterConverter are not used by the generated methods.
+//
+// Usage:
+//
+//	httpheader-gen -type=Options,Metadata [-output=file] [dir]
+//
+// It is typically used with go generate:
+//
+//	//go:generate httpheader-gen -type=Options
+//
+// The types must be structs declared in the package in dir, the current
+// directory by default. Their fields may be strings, booleans, integers,
+// floats, time.Time, time.Duration and types implementing
+// encoding.TextMarshaler and encoding.TextUnmarshaler, as well as pointers
+// to and slices of those, http.Header, "prefix" maps, nested and embedded
+// structs, and types implementing httpheader.Encoder and httpheader.Decoder.
+// Types declared in other packages must be among those listed. The methods
+// are written to <type>_httpheader.go, for the first type given.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	typeNames = flag.String("type", "", "comma-separated list of type names; must be set")
+	output    = flag.String("output", "", "output file name; default <dir>/<type>_httpheader.go")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage of httpheader-gen:\n")
+	fmt.Fprintf(os.Stderr, "\thttpheader-gen -type=T [-output=file] [dir]\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("httpheader-gen: ")
+	flag.Usage = usage
+	flag.Parse()
+	if *typeNames == "" || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+	types := strings.Split(*typeNames, ",")
+
+	src, err := generate(dir, types)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	out := *output
+	if out == "" {
+		out = filepath.Join(dir, strings.ToLower(types[0])+"_httpheader.go")
+	}
+	if err := os.WriteFile(out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
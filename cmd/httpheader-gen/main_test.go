@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	dir := filepath.Join("..", "..", "internal", "gentest")
+	want, err := os.ReadFile(filepath.Join(dir, "generated.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := generate(dir, []string{"Options", "Nested", "Lists", "Fields"})
+	if err != nil {
+		t.Fatalf("generate returned error: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("generated code differs from %s, run go generate", filepath.Join(dir, "generated.go"))
+	}
+}
+
+func TestGenerate_errors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"type T int", "T is not a struct type"},
+		{"type U struct{}", "type T not found"},
+		{"type T struct{ C chan int }", "C: unsupported type chan"},
+		{"type T struct{ A [2]int }", "A: unsupported array type"},
+		{"type T struct{ B bytes.Buffer }", "B: unsupported type bytes.Buffer from another package"},
+		{"type T struct{ P *struct{ A int } }", "P: unsupported pointer to struct"},
+		{"type T struct{ M map[string]int }", "M: unsupported type map[string]int"},
+		{"type T struct{ S string `header:\"X-S,dup=uniqe\"` }", `S: unknown dup option "uniqe"`},
+		{"type T struct{ Next *T }", "Next: unsupported type *T"},
+		{"type T struct{ D Date }; type Date time.Time", "D: unsupported type Date based on time.Time"},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		src := "package p\n\nimport (\n\t\"bytes\"\n\t\"time\"\n)\n\nvar _ bytes.Buffer\nvar _ time.Time\n\n" + tt.src + "\n"
+		if err := os.WriteFile(filepath.Join(dir, "p.go"), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := generate(dir, []string{"T"})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("generate(%q) returned error %v, want %q", tt.src, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// typeKind classifies the types supported by the generated code.
+type typeKind int
+
+const (
+	kUnsupported typeKind = iota
+	kString
+	kBool
+	kInt
+	kUint
+	kFloat
+	kTime
+	kDuration
+	kHeader
+	kStruct
+	kPtr
+	kSlice
+	kMap
+)
+
+// goType is a resolved type of a struct field.
+type goType struct {
+	kind typeKind
+	expr string // Go syntax of the type in the generated file
+	name string // name of the type if it is declared in the package
+	bits int    // size of integer and float types
+	elem *goType
+	key  *goType
+	st   *ast.StructType
+	file *ast.File // file declaring st
+	why  string    // reason why the type is not supported
+}
+
+var basicTypes = map[string]goType{
+	"string":  {kind: kString},
+	"bool":    {kind: kBool},
+	"int":     {kind: kInt, bits: 64},
+	"int8":    {kind: kInt, bits: 8},
+	"int16":   {kind: kInt, bits: 16},
+	"int32":   {kind: kInt, bits: 32},
+	"rune":    {kind: kInt, bits: 32},
+	"int64":   {kind: kInt, bits: 64},
+	"uint":    {kind: kUint, bits: 64},
+	"uint8":   {kind: kUint, bits: 8},
+	"byte":    {kind: kUint, bits: 8},
+	"uint16":  {kind: kUint, bits: 16},
+	"uint32":  {kind: kUint, bits: 32},
+	"uint64":  {kind: kUint, bits: 64},
+	"float32": {kind: kFloat, bits: 32},
+	"float64": {kind: kFloat, bits: 64},
+}
+
+// typeSpec is a type declared in the package.
+type typeSpec struct {
+	spec *ast.TypeSpec
+	file *ast.File
+}
+
+// packageInfo holds the type and method declarations of a package.
+type packageInfo struct {
+	name     string
+	specs    map[string]typeSpec
+	methods  map[string]map[string]bool // methods by type, true for pointer receivers
+	resolved map[string]*goType
+}
+
+// loadPackage parses the package in dir, ignoring test files.
+func loadPackage(dir string) (*packageInfo, error) {
+	fset := token.NewFileSet()
+	notTest := func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}
+	pkgs, err := parser.ParseDir(fset, dir, notTest, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("%d packages found in %s", len(pkgs), dir)
+	}
+
+	p := &packageInfo{
+		specs:    make(map[string]typeSpec),
+		methods:  make(map[string]map[string]bool),
+		resolved: make(map[string]*goType),
+	}
+	for name, pkg := range pkgs {
+		p.name = name
+		for _, file := range pkg.Files {
+			p.addFile(file)
+		}
+	}
+	return p, nil
+}
+
+func (p *packageInfo) addFile(file *ast.File) {
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				if ts, ok := spec.(*ast.TypeSpec); ok {
+					p.specs[ts.Name.Name] = typeSpec{ts, file}
+				}
+			}
+		case *ast.FuncDecl:
+			if decl.Recv == nil || len(decl.Recv.List) != 1 {
+				continue
+			}
+			recv, ptr := decl.Recv.List[0].Type, false
+			if star, ok := recv.(*ast.StarExpr); ok {
+				recv, ptr = star.X, true
+			}
+			if id, ok := recv.(*ast.Ident); ok {
+				p.addMethod(id.Name, decl.Name.Name, ptr)
+			}
+		}
+	}
+}
+
+func (p *packageInfo) addMethod(typ, method string, ptr bool) {
+	if p.methods[typ] == nil {
+		p.methods[typ] = make(map[string]bool)
+	}
+	p.methods[typ][method] = ptr
+}
+
+// hasMethod reports whether t or a pointer to t has the method m.
+func (p *packageInfo) hasMethod(t *goType, m string) bool {
+	if t.name == "" {
+		return false
+	}
+	_, ok := p.methods[t.name][m]
+	return ok
+}
+
+// hasValueMethod reports whether t has the method m.
+func (p *packageInfo) hasValueMethod(t *goType, m string) bool {
+	ptr, ok := p.methods[t.name][m]
+	return t.name != "" && ok && !ptr
+}
+
+// isGenerated reports whether t, or the type t points to, has the methods
+// generated by httpheader-gen. Its fields are handled in place like those of
+// a plain struct, see the hasGeneratedMethods function of the httpheader
+// package.
+func (p *packageInfo) isGenerated(t *goType) bool {
+	return p.hasValueMethod(indirect(t), "HTTPHeaderGenerated")
+}
+
+func unsupported(format string, args ...interface{}) *goType {
+	return &goType{kind: kUnsupported, why: fmt.Sprintf(format, args...)}
+}
+
+// resolve returns the type of expr, written in file.
+func (p *packageInfo) resolve(expr ast.Expr, file *ast.File) *goType {
+	switch x := expr.(type) {
+	case *ast.Ident:
+		if _, ok := p.specs[x.Name]; ok {
+			return p.named(x.Name)
+		}
+		if b, ok := basicTypes[x.Name]; ok {
+			t := b
+			t.expr = x.Name
+			return &t
+		}
+		return unsupported("unsupported type %s", x.Name)
+	case *ast.SelectorExpr:
+		id, ok := x.X.(*ast.Ident)
+		if !ok {
+			break
+		}
+		switch importPath(file, id.Name) + "." + x.Sel.Name {
+		case "time.Time":
+			return &goType{kind: kTime, expr: "time.Time"}
+		case "time.Duration":
+			return &goType{kind: kDuration, expr: "time.Duration", bits: 64}
+		case "net/http.Header":
+			return &goType{kind: kHeader, expr: "http.Header"}
+		}
+		return unsupported("unsupported type %s.%s from another package", id.Name, x.Sel.Name)
+	case *ast.StarExpr:
+		elem := p.resolve(x.X, file)
+		if elem.kind == kUnsupported {
+			return elem
+		}
+		return &goType{kind: kPtr, expr: "*" + elem.expr, elem: elem}
+	case *ast.ArrayType:
+		if x.Len != nil {
+			return unsupported("unsupported array type")
+		}
+		elem := p.resolve(x.Elt, file)
+		if elem.kind == kUnsupported {
+			return elem
+		}
+		return &goType{kind: kSlice, expr: "[]" + elem.expr, elem: elem}
+	case *ast.MapType:
+		key, elem := p.resolve(x.Key, file), p.resolve(x.Value, file)
+		if key.kind == kUnsupported {
+			return key
+		}
+		if elem.kind == kUnsupported {
+			return elem
+		}
+		return &goType{kind: kMap, expr: "map[" + key.expr + "]" + elem.expr, key: key, elem: elem}
+	case *ast.StructType:
+		return &goType{kind: kStruct, expr: "struct{...}", st: x, file: file}
+	case *ast.ParenExpr:
+		return p.resolve(x.X, file)
+	}
+	return unsupported("unsupported type %s", types.ExprString(expr))
+}
+
+// named returns the type declared in the package as name.
+func (p *packageInfo) named(name string) *goType {
+	if t, ok := p.resolved[name]; ok {
+		return t
+	}
+	t := unsupported("unsupported recursive type %s", name)
+	p.resolved[name] = t
+
+	spec := p.specs[name]
+	u := p.resolve(spec.spec.Type, spec.file)
+	if spec.spec.Assign.IsValid() { // alias
+		*t = *u
+		return t
+	}
+	*t = *u
+	t.name, t.expr = name, name
+	switch u.kind {
+	case kTime, kHeader:
+		*t = *unsupported("unsupported type %s based on %s", name, u.expr)
+	case kDuration:
+		t.kind = kInt
+	}
+	return t
+}
+
+// importPath returns the path of the package imported as name in file.
+func importPath(file *ast.File, name string) string {
+	for _, imp := range file.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		if imp.Name != nil {
+			if imp.Name.Name == name {
+				return path
+			}
+			continue
+		}
+		if path == name || strings.HasSuffix(path, "/"+name) {
+			return path
+		}
+	}
+	return ""
+}
+
+// indirect returns the type t points to, or t.
+func indirect(t *goType) *goType {
+	for t.kind == kPtr {
+		t = t.elem
+	}
+	return t
+}
+
+// isPrefixMap reports whether t is a map[string]string or a
+// map[string][]string, see the "prefix" option.
+func isPrefixMap(t *goType) bool {
+	if t.kind != kMap || t.key.kind != kString {
+		return false
+	}
+	elem := t.elem
+	if elem.kind == kSlice {
+		elem = elem.elem
+	}
+	return elem.kind == kString
+}
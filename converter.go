@@ -0,0 +1,116 @@
+package httpheader
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// EncodeFunc returns the Header field value of v.
+type EncodeFunc func(v reflect.Value) (string, error)
+
+// DecodeFunc returns the value decoded from the Header field value s.
+type DecodeFunc func(s string) (reflect.Value, error)
+
+// converter is a registered EncodeFunc and DecodeFunc pair.
+type converter struct {
+	enc EncodeFunc
+	dec DecodeFunc
+}
+
+// converters is a registry of converters by type. Lookups don't lock: the
+// registry map is replaced, never modified, by register.
+type converters struct {
+	mu sync.Mutex   // serializes register
+	m  atomic.Value // map[reflect.Type]converter
+}
+
+// converterGen is incremented by every registration, invalidating the field
+// caches which depend on the registered types.
+var converterGen uint64
+
+var defaultConverters converters
+
+// RegisterConverter makes values of type t encoded by enc and decoded by dec
+// in every HeaderEncoder and HeaderDecoder, including the ones used by Header
+// and Decode, in preference to the default rules. Converters registered on an
+// encoder or decoder take precedence over the ones registered here.
+//
+// Either function may be nil to leave the other direction unchanged. t may be
+// a pointer type, in which case values of type t are passed to enc and
+// returned by dec. Registered types are also used for slice and array
+// elements.
+//
+// RegisterConverter is safe for concurrent use, but is meant to be called
+// during initialization.
+func RegisterConverter(t reflect.Type, enc EncodeFunc, dec DecodeFunc) {
+	defaultConverters.register(t, enc, dec)
+}
+
+// RegisterEncodeFunc makes values of type t encoded by fn in e, see
+// RegisterConverter. It is safe for concurrent use.
+func (e *HeaderEncoder) RegisterEncodeFunc(t reflect.Type, fn EncodeFunc) {
+	e.converters.register(t, fn, nil)
+}
+
+// RegisterDecodeFunc makes values of type t decoded by fn in d, see
+// RegisterConverter. It is safe for concurrent use.
+func (d *HeaderDecoder) RegisterDecodeFunc(t reflect.Type, fn DecodeFunc) {
+	d.converters.register(t, nil, fn)
+}
+
+func (r *converters) register(t reflect.Type, enc EncodeFunc, dec DecodeFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old, _ := r.m.Load().(map[reflect.Type]converter)
+	m := make(map[reflect.Type]converter, len(old)+1)
+	for k, v := range old {
+		m[k] = v
+	}
+	conv := m[t]
+	if enc != nil {
+		conv.enc = enc
+	}
+	if dec != nil {
+		conv.dec = dec
+	}
+	m[t] = conv
+	r.m.Store(m)
+	atomic.AddUint64(&converterGen, 1)
+}
+
+func (r *converters) lookup(t reflect.Type) converter {
+	m, _ := r.m.Load().(map[reflect.Type]converter)
+	return m[t]
+}
+
+// encodeFunc returns the EncodeFunc registered for t, or nil.
+func (c *config) encodeFunc(t reflect.Type) EncodeFunc {
+	if fn := c.converters.lookup(t).enc; fn != nil {
+		return fn
+	}
+	return defaultConverters.lookup(t).enc
+}
+
+// decodeFunc returns the DecodeFunc registered for t, or nil.
+func (c *config) decodeFunc(t reflect.Type) DecodeFunc {
+	if fn := c.converters.lookup(t).dec; fn != nil {
+		return fn
+	}
+	return defaultConverters.lookup(t).dec
+}
+
+// hasEncodeFunc reports whether an EncodeFunc is registered for t or for the
+// type of any value t points to.
+func (c *config) hasEncodeFunc(t reflect.Type) bool {
+	for {
+		if c.encodeFunc(t) != nil {
+			return true
+		}
+		if t.Kind() != reflect.Ptr {
+			return false
+		}
+		t = t.Elem()
+	}
+}
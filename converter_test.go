@@ -0,0 +1,172 @@
+package httpheader
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+type money struct {
+	Cents int64
+}
+
+func init() {
+	RegisterConverter(reflect.TypeOf(money{}), func(v reflect.Value) (string, error) {
+		m := v.Interface().(money)
+		return fmt.Sprintf("%d.%02d", m.Cents/100, m.Cents%100), nil
+	}, func(s string) (reflect.Value, error) {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(money{int64(f*100 + 0.5)}), nil
+	})
+}
+
+type moneyStruct struct {
+	A money
+	B *money
+	C []money
+	D []*money
+	E **money
+}
+
+func TestRegisterConverter(t *testing.T) {
+	m := &money{250}
+	s := moneyStruct{
+		A: money{1234},
+		B: &money{5},
+		C: []money{{100}, {199}},
+		D: []*money{{1}},
+		E: &m,
+	}
+	h := http.Header{
+		"A": []string{"12.34"},
+		"B": []string{"0.05"},
+		"C": []string{"1.00", "1.99"},
+		"D": []string{"0.01"},
+		"E": []string{"2.50"},
+	}
+
+	v, err := Header(s)
+	if err != nil {
+		t.Errorf("Header(%+v) returned error: %v", s, err)
+	}
+	if !reflect.DeepEqual(h, v) {
+		t.Errorf("Header(%+v) returned %v, want %v", s, v, h)
+	}
+
+	var got moneyStruct
+	if err := Decode(h, &got); err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+	if !reflect.DeepEqual(s, got) {
+		t.Errorf("want/got:\n%#v\n%#v", s, got)
+	}
+
+	err = Decode(http.Header{"C": []string{"1", "x"}}, &got)
+	if e, ok := err.(*DecodeError); !ok || e.Field != "C" {
+		t.Errorf("Decode returned %#v, want *DecodeError for field C", err)
+	}
+}
+
+func TestRegisterConverter_pointerType(t *testing.T) {
+	type counter struct {
+		N int
+	}
+	type counterStruct struct {
+		A *counter
+		B []*counter
+	}
+
+	e := NewEncoder()
+	e.RegisterEncodeFunc(reflect.TypeOf(&counter{}), func(v reflect.Value) (string, error) {
+		return strconv.Itoa(v.Interface().(*counter).N), nil
+	})
+	d := NewDecoder()
+	d.RegisterDecodeFunc(reflect.TypeOf(&counter{}), func(s string) (reflect.Value, error) {
+		n, err := strconv.Atoi(s)
+		return reflect.ValueOf(&counter{n}), err
+	})
+
+	s := counterStruct{A: &counter{1}, B: []*counter{{2}, nil}}
+	h := http.Header{"A": []string{"1"}, "B": []string{"2", ""}}
+	v, err := e.Encode(s)
+	if err != nil {
+		t.Errorf("Encode(%+v) returned error: %v", s, err)
+	}
+	if !reflect.DeepEqual(h, v) {
+		t.Errorf("Encode(%+v) returned %v, want %v", s, v, h)
+	}
+
+	var got counterStruct
+	h = http.Header{"A": []string{"1"}, "B": []string{"2", "3"}}
+	if err := d.Decode(h, &got); err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+	if want := (counterStruct{A: &counter{1}, B: []*counter{{2}, {3}}}); !reflect.DeepEqual(want, got) {
+		t.Errorf("want/got:\n%#v\n%#v", want, got)
+	}
+}
+
+func TestRegisterConverter_invalidatesCache(t *testing.T) {
+	type version struct {
+		Major, Minor int
+	}
+	type versionStruct struct {
+		V version
+	}
+	s := versionStruct{version{1, 2}}
+
+	e := NewEncoder()
+	v, err := e.Encode(s)
+	if err != nil {
+		t.Errorf("Encode(%+v) returned error: %v", s, err)
+	}
+	if want := (http.Header{"Major": []string{"1"}, "Minor": []string{"2"}}); !reflect.DeepEqual(want, v) {
+		t.Errorf("Encode(%+v) returned %v, want %v", s, v, want)
+	}
+
+	e.RegisterEncodeFunc(reflect.TypeOf(version{}), func(v reflect.Value) (string, error) {
+		ver := v.Interface().(version)
+		return fmt.Sprintf("%d.%d", ver.Major, ver.Minor), nil
+	})
+	v, err = e.Encode(s)
+	if err != nil {
+		t.Errorf("Encode(%+v) returned error: %v", s, err)
+	}
+	if want := (http.Header{"V": []string{"1.2"}}); !reflect.DeepEqual(want, v) {
+		t.Errorf("Encode(%+v) returned %v, want %v", s, v, want)
+	}
+}
+
+func TestRegisterConverter_concurrent(t *testing.T) {
+	type id int
+	d := NewDecoder()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			d.RegisterDecodeFunc(reflect.TypeOf(id(0)), func(s string) (reflect.Value, error) {
+				return reflect.ValueOf(id(len(s))), nil
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			var got struct{ ID id }
+			if err := d.Decode(http.Header{"Id": []string{"42"}}, &got); err != nil {
+				t.Errorf("Decode returned error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var got struct{ ID id }
+	if err := d.Decode(http.Header{"Id": []string{"abc"}}, &got); err != nil || got.ID != 3 {
+		t.Errorf("Decode returned %v, %v, want 3", got.ID, err)
+	}
+}
@@ -1,15 +1,23 @@
 package httpheader
 
 import (
+	"encoding"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
-	"net/textproto"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/mozillazg/go-httpheader/internal/fieldtag"
 )
 
+var decoderType = reflect.TypeOf(new(Decoder)).Elem()
+var textUnmarshalerType = reflect.TypeOf(new(encoding.TextUnmarshaler)).Elem()
+
 // Decoder is an interface implemented by any type that wishes to decode
 // itself from Header fields in a non-standard way.
 type Decoder interface {
@@ -18,7 +26,136 @@ type Decoder interface {
 
 // Decode expects to be passed an http.Header and a struct, and parses
 // header into the struct recursively using the same rules as Header (see above)
+//
+// Values implementing encoding.TextUnmarshaler, including slice and array
+// elements, are decoded with their UnmarshalText method.
+//
+// Maps with the "prefix" option collect every Header field whose name starts
+// with the prefix (case-insensitively), keyed by the rest of the name.
+//
+// http.Header fields without the "prefix" option collect a copy of every
+// Header field that is not decoded by another struct field, so that they
+// decode what Header adds from them. For example:
+//
+//	// Extra holds the Header fields other than "X-Name".
+//	type Options struct {
+//		Name  string `header:"X-Name"`
+//		Extra http.Header
+//	}
+//
+// Struct fields with the "required" option must have their Header field
+// present, otherwise Decode returns a *MissingHeaderError listing all the
+// missing Header fields. For example:
+//
+//	// Decode fails if the "X-Tenant-Id" Header field is missing.
+//	TenantID string `header:"X-Tenant-Id,required"`
+//
+// Slice and array fields with the "comma" or "sep=<separator>" option are
+// decoded from the list elements of every value of their Header field, so
+// that both "Accept-Encoding: gzip, br" and multiple Header values are
+// accepted. Separators in quoted strings are ignored, and whitespace around
+// the elements is trimmed.
+//
+// time.Time values are decoded according to the same options as Header.
+// Without any of them, the obsolete RFC 850 and asctime date formats are
+// also accepted, as required by RFC 9110 section 5.6.7.
+//
+// time.Duration values are decoded according to the same options as Header.
+// Without any of them, both Go durations and integer nanoseconds are
+// accepted.
+//
+// Struct fields with the "default" option are decoded from its value when
+// their Header field is missing. The values of slice and array fields are
+// separated by ";", see WithDefaultSeparator. For example:
+//
+//	// Timeout is 30 when the "X-Timeout" Header field is missing.
+//	Timeout int `header:"X-Timeout,default=30"`
+//
+//	// Encoding is ["gzip", "br"] when the "X-Encoding" Header field is missing.
+//	Encoding []string `header:"X-Encoding,default=gzip;br"`
+//
+// Fields that hold a single value, such as strings, numbers and times, are
+// decoded from the first value of their Header field, unless the
+// "dup=<policy>" option or the WithDuplicates option selects another
+// DuplicatePolicy: "last", "join" or "unique". Decode returns an error for
+// other values of the option. For example:
+//
+//	// Decode returns a *DuplicateHeaderError if "X-Forwarded-Proto" has more
+//	// than one value.
+//	Proto string `header:"X-Forwarded-Proto,dup=unique"`
+//
+//	// Via holds the values of "Via" joined by ";".
+//	Via string `header:"Via,dup=join,sep=;"`
+//
+// Struct fields with the "raw" option are decoded from the Header field named
+// exactly as in their tag, instead of its canonical format, see WithKeyMode
+// for the other ways to look up Header fields.
+//
+// Decode also accepts a pointer to a map with string keys, such as an
+// http.Header, which it fills with an entry per Header field, keyed by its
+// canonical name, see WithKeyCanonicalizer and WithKeyMode. The entries are
+// decoded like struct fields without options: strings from the first value
+// of their Header field and slices from all of them, Header fields whose
+// names have the same canonical format being merged. For example:
+//
+//	// m holds the first value of each Header field, keyed by its name in
+//	// lower case.
+//	var m map[string]string
+//	err := NewDecoder(WithKeyCanonicalizer(strings.ToLower)).Decode(header, &m)
+//
+// Decode does not modify header. Fields that hold a single value are decoded
+// from the same value of their Header field, even if several fields share
+// the same Header field name, see DecodeConsume.
+//
+// If v points to a struct whose methods are generated by the httpheader-gen
+// command, its DecodeHeader method is called instead of using reflection.
+// Other structs implementing Decoder are decoded by reflection, the method
+// only being used for the fields of their type. Fields of generated struct
+// types are decoded by reflection like plain structs.
 func Decode(header http.Header, v interface{}) error {
+	if m, ok := structDecoder(v); ok {
+		return m.DecodeHeader(header, "")
+	}
+	return defaultDecoder.Decode(header, v)
+}
+
+// DecodeConsume is like Decode, but each field that holds a single value
+// consumes the first remaining value of its Header field, so that fields
+// sharing the same Header field name are decoded from successive values.
+// Fields with the "last" DuplicatePolicy consume the last value instead, and
+// those with the "join" one all the values. This is the reverse of Header, which encodes such fields as multiple
+// values of the same name. header is not modified.
+func DecodeConsume(header http.Header, v interface{}) error {
+	return consumeDecoder.Decode(header, v)
+}
+
+// HeaderDecoder decodes http.Header fields into structs with a set of
+// options. It is safe for concurrent use by multiple goroutines.
+type HeaderDecoder struct {
+	config
+}
+
+var (
+	defaultDecoder = NewDecoder()
+	consumeDecoder = NewDecoder(WithConsume())
+)
+
+// NewDecoder returns a HeaderDecoder configured by opts.
+func NewDecoder(opts ...Option) *HeaderDecoder {
+	d := new(HeaderDecoder)
+	d.init(d.decodeKind, opts)
+	return d
+}
+
+// Decode parses header into the struct v, using the rules described in the
+// documentation for the Decode function.
+func (d *HeaderDecoder) Decode(header http.Header, v interface{}) error {
+	return d.decodePtr(header, nil, v)
+}
+
+// decodePtr parses header, and trailer if it is not nil, into the struct
+// that v points to.
+func (d *HeaderDecoder) decodePtr(header, trailer http.Header, v interface{}) error {
 	val := reflect.ValueOf(v)
 	if val.Kind() != reflect.Ptr || val.IsNil() {
 		return errors.New("v should be a pointer and should not be nil")
@@ -27,140 +164,905 @@ func Decode(header http.Header, v interface{}) error {
 	for val.Kind() == reflect.Ptr {
 		val = val.Elem()
 	}
+	return d.decode(header, trailer, val)
+}
 
+// decode parses header into the struct or the map val. The fields with the
+// "trailer" option are decoded from trailer instead if it is not nil.
+func (d *HeaderDecoder) decode(header, trailer http.Header, val reflect.Value) error {
+	if d.filter != nil {
+		header = filterHeader(header, d.filter)
+		if trailer != nil {
+			trailer = filterHeader(trailer, d.filter)
+		}
+	}
+	if val.Kind() == reflect.Map && val.Type().Key().Kind() == reflect.String {
+		return d.decodeMap(header, val)
+	}
 	if val.Kind() != reflect.Struct {
 		return fmt.Errorf("v is not a struct %+v", val.Kind())
 	}
-	return parseValue(header, val)
+	ds := decodeState{HeaderDecoder: d, header: header, trailer: trailer, typ: val.Type()}
+	if d.consume {
+		ds.header = cloneHeader(header)
+		if trailer != nil {
+			ds.trailer = cloneHeader(trailer)
+		}
+	}
+	if err := ds.parseValue(val, ""); err != nil {
+		return err
+	}
+	if ds.missing != nil {
+		if err := ds.fail(ds.missing); err != nil {
+			return err
+		}
+	}
+	if d.disallowUnknown {
+		if err := d.unknownHeaders(header, val.Type()); err != nil {
+			if err := ds.fail(err); err != nil {
+				return err
+			}
+		}
+	}
+	if ds.errs != nil {
+		return &DecodeErrors{Struct: val.Type().Name(), Errors: ds.errs}
+	}
+	return nil
 }
 
-// parseValue populates the struct fields in val from the header fields.
-// Embedded structs are followed recursively (using the rules defined in the
-// Values function documentation) breadth-first.
-func parseValue(header http.Header, val reflect.Value) error {
-	var embedded []reflect.Value
-
-	typ := val.Type()
-	for i := 0; i < typ.NumField(); i++ {
-		sf := typ.Field(i)
-		if sf.PkgPath != "" && !sf.Anonymous { // unexported
-			continue
+// decodeMap parses header into the map val, keyed by the canonical format of
+// the Header field names. The values of Header fields whose names have the
+// same canonical format are merged.
+func (d *HeaderDecoder) decodeMap(header http.Header, val reflect.Value) error {
+	names := make([]string, 0, len(header))
+	for k, vs := range header {
+		if len(vs) > 0 {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	var keys []string
+	vals := make(map[string][]string)
+	for _, k := range names {
+		key := d.canonicalKey(k)
+		if _, ok := vals[key]; !ok {
+			keys = append(keys, key)
 		}
+		vals[key] = append(vals[key], header[k]...)
+	}
 
-		sv := val.Field(i)
-		tag := sf.Tag.Get(tagName)
-		if tag == "-" {
+	t := val.Type()
+	if val.IsNil() {
+		val.Set(reflect.MakeMap(t))
+	}
+	ds := decodeState{HeaderDecoder: d, header: header, typ: t}
+	for _, key := range keys {
+		ev := reflect.New(t.Elem()).Elem()
+		if err := d.fillValues(ev, nil, vals[key]); err != nil {
+			err = &DecodeError{
+				Struct: t.Name(),
+				Field:  key,
+				Header: key,
+				Value:  strings.Join(vals[key], ", "),
+				Err:    err,
+			}
+			if err := ds.fail(err); err != nil {
+				return err
+			}
 			continue
 		}
-		name, opts := parseTag(tag)
-		if name == "" {
-			if sf.Anonymous && sv.Kind() == reflect.Struct {
-				// save embedded struct for later processing
-				embedded = append(embedded, sv)
-				continue
+		mk := reflect.New(t.Key()).Elem()
+		mk.SetString(key)
+		val.SetMapIndex(mk, ev)
+	}
+	if ds.errs != nil {
+		return &DecodeErrors{Struct: t.Name(), Errors: ds.errs}
+	}
+	return nil
+}
+
+// filterHeader returns the Header fields of header for which keep returns
+// true.
+func filterHeader(header http.Header, keep func(name string) bool) http.Header {
+	h := make(http.Header, len(header))
+	for k, vs := range header {
+		if keep(k) {
+			h[k] = vs
+		}
+	}
+	return h
+}
+
+// DuplicatePolicy selects the value decoded into a field holding a single
+// value when its Header field has more than one, see WithDuplicates and the
+// "dup" option.
+type DuplicatePolicy int
+
+const (
+	// DuplicateFirst decodes the first value. This is the default.
+	DuplicateFirst DuplicatePolicy = iota
+	// DuplicateLast decodes the last value, e.g. for Header fields appended
+	// to by proxies.
+	DuplicateLast
+	// DuplicateJoin decodes the values joined by ", ", or by the separator
+	// of the "sep" option.
+	DuplicateJoin
+	// DuplicateUnique reports more than one value as a
+	// *DuplicateHeaderError, e.g. for security-sensitive Header fields.
+	DuplicateUnique
+)
+
+// duplicatePolicies maps the values of the "dup" option to the
+// DuplicatePolicy they select.
+var duplicatePolicies = map[string]DuplicatePolicy{
+	"first":  DuplicateFirst,
+	"last":   DuplicateLast,
+	"join":   DuplicateJoin,
+	"unique": DuplicateUnique,
+}
+
+// duplicateValues returns the values of vals to decode according to policy
+// and the tag options opts, or nil and false if policy is DuplicateUnique
+// and there is more than one value.
+func duplicateValues(policy DuplicatePolicy, opts tagOptions, vals []string) ([]string, bool) {
+	if len(vals) < 2 {
+		return vals, true
+	}
+	switch policy {
+	case DuplicateLast:
+		return vals[len(vals)-1:], true
+	case DuplicateJoin:
+		sep, ok := opts.Value("sep")
+		if !ok {
+			sep = ", "
+		}
+		return []string{strings.Join(vals, sep)}, true
+	case DuplicateUnique:
+		return nil, false
+	}
+	return vals, true
+}
+
+// DuplicateHeaderError is returned by Decode when a Header field has more
+// than one value but its struct field has the "dup=unique" option, or the
+// decoder the DuplicateUnique policy.
+type DuplicateHeaderError struct {
+	Struct string   // name of the struct type passed to Decode
+	Field  string   // path of the struct field, e.g. "Outer.Inner.Retry"
+	Header string   // Header field name
+	Values []string // values of the Header field
+}
+
+func (e *DuplicateHeaderError) Error() string {
+	field := e.Field
+	if e.Struct != "" {
+		field = e.Struct + "." + field
+	}
+	return fmt.Sprintf("httpheader: header %s has %d values, field %s allows only one",
+		e.Header, len(e.Values), field)
+}
+
+// DecodeError describes a Header field that could not be decoded into a
+// struct field.
+type DecodeError struct {
+	Struct string // name of the struct type passed to Decode
+	Field  string // path of the struct field, e.g. "Outer.Inner.Retry"
+	Header string // Header field name
+	Value  string // Header field value, multiple values are joined by ", "
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	field := e.Field
+	if e.Struct != "" {
+		field = e.Struct + "." + field
+	}
+	return fmt.Sprintf("httpheader: cannot decode header %s value %q into field %s: %v",
+		e.Header, e.Value, field, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// MissingHeaderError is returned by Decode when the Header fields of one or
+// more struct fields with the "required" option are missing.
+type MissingHeaderError struct {
+	Struct  string   // name of the struct type passed to Decode
+	Headers []string // missing Header field names
+	Fields  []string // paths of the struct fields, in the order of Headers
+}
+
+func (e *MissingHeaderError) Error() string {
+	return "httpheader: missing required header: " + strings.Join(e.Headers, ", ")
+}
+
+// UnknownHeaderError is returned by a HeaderDecoder created with the
+// WithDisallowUnknownHeaders option when Header fields are not decoded into
+// any struct field.
+type UnknownHeaderError struct {
+	Struct  string   // name of the struct type passed to Decode
+	Headers []string // unknown Header field names, sorted
+}
+
+func (e *UnknownHeaderError) Error() string {
+	return "httpheader: unknown header: " + strings.Join(e.Headers, ", ")
+}
+
+// DecodeErrors is returned by a HeaderDecoder created with the WithAllErrors
+// option when one or more struct fields could not be decoded. Errors holds a
+// *DecodeError or a *DuplicateHeaderError for each of them, followed by the
+// *MissingHeaderError and *UnknownHeaderError found, if any.
+type DecodeErrors struct {
+	Struct string // name of the struct type passed to Decode
+	Errors []error
+}
+
+func (e *DecodeErrors) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = strings.TrimPrefix(err.Error(), "httpheader: ")
+	}
+	return fmt.Sprintf("httpheader: %d errors: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the errors listed, so that errors.Is and errors.As, as of
+// Go 1.20, match any of them.
+func (e *DecodeErrors) Unwrap() []error {
+	return e.Errors
+}
+
+// Is reports whether any of the errors listed matches target, so that
+// errors.Is matches them before Go 1.20 too.
+func (e *DecodeErrors) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the errors listed that matches target, so that
+// errors.As matches them before Go 1.20 too.
+func (e *DecodeErrors) As(target interface{}) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// FieldError describes a Header field that could not be decoded, see
+// DecodeErrors.Fields.
+type FieldError struct {
+	Header string // Header field name
+	Field  string // path of the struct field, empty for unknown Header fields
+	Reason string // "missing", "unknown", "duplicate" or the error decoding the value
+}
+
+// Fields returns one FieldError per Header field in error.
+func (e *DecodeErrors) Fields() []FieldError {
+	var fields []FieldError
+	for _, err := range e.Errors {
+		switch err := err.(type) {
+		case *DecodeError:
+			fields = append(fields, FieldError{Header: err.Header, Field: err.Field, Reason: err.Err.Error()})
+		case *DuplicateHeaderError:
+			fields = append(fields, FieldError{Header: err.Header, Field: err.Field, Reason: "duplicate"})
+		case *MissingHeaderError:
+			for i, h := range err.Headers {
+				fields = append(fields, FieldError{Header: h, Field: err.Fields[i], Reason: "missing"})
+			}
+		case *UnknownHeaderError:
+			for _, h := range err.Headers {
+				fields = append(fields, FieldError{Header: h, Reason: "unknown"})
 			}
-			name = sf.Name
 		}
+	}
+	return fields
+}
+
+// transportHeaders are the Header fields allowed by the
+// WithDisallowUnknownHeaders option by default: the hop-by-hop fields of
+// RFC 9110 section 7.6.1 and the fields set by clients, servers and proxies.
+var transportHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Connection",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+	"Host",
+	"User-Agent",
+	"Accept-Encoding",
+	"Content-Length",
+	"Date",
+	"Via",
+	"Forwarded",
+	"X-Forwarded-For",
+	"X-Forwarded-Host",
+	"X-Forwarded-Proto",
+}
 
-		if opts.Contains("omitempty") && header.Get(name) == "" {
+// unknownHeaders returns an *UnknownHeaderError listing the Header fields
+// of header that are neither allowed nor decoded by a field of struct type
+// t, or nil if there is none.
+func (d *HeaderDecoder) unknownHeaders(header http.Header, t reflect.Type) error {
+	known := make(map[string]bool)
+	var prefixes []string
+	if d.knownHeaders(t, known, &prefixes) {
+		return nil // every Header field is decoded
+	}
+
+	var unknown []string
+	for k, vs := range header {
+		if len(vs) == 0 || known[d.knownKey(k)] || d.allowedHeaders[http.CanonicalHeaderKey(k)] {
 			continue
 		}
+		if !hasAnyPrefix(k, prefixes, d.foldPrefix()) {
+			unknown = append(unknown, k)
+		}
+	}
+	if unknown == nil {
+		return nil
+	}
+	sort.Strings(unknown)
+	return &UnknownHeaderError{Struct: t.Name(), Headers: unknown}
+}
 
-		// Decoder interface
-		addr := sv
-		if addr.Kind() != reflect.Ptr && addr.Type().Name() != "" && addr.CanAddr() {
-			addr = addr.Addr()
+// knownHeaders adds the Header field names decoded by the fields of struct
+// type t to known, and the prefixes of its "prefix" fields to prefixes. It
+// reports whether t has an http.Header field collecting the other Header
+// fields.
+func (d *HeaderDecoder) knownHeaders(t reflect.Type, known map[string]bool, prefixes *[]string) (rest bool) {
+	fields, _ := d.cachedTypeFields(t) // reported by parseValue
+	for i := range fields {
+		f := &fields[i]
+		switch f.kind {
+		case kindStruct:
+			if f.omitEmpty {
+				known[d.knownKey(d.key(f))] = true
+			}
+			if d.knownHeaders(f.typ, known, prefixes) {
+				rest = true
+			}
+		case kindHeader:
+			rest = true
+		case kindPrefix:
+			*prefixes = append(*prefixes, d.key(f))
+		default:
+			known[d.knownKey(d.key(f))] = true
 		}
-		if addr.Type().NumMethod() > 0 && addr.CanInterface() {
-			if m, ok := addr.Interface().(Decoder); ok {
-				if err := m.DecodeHeader(header, name); err != nil {
+	}
+	return rest
+}
+
+// hasKeyPrefix reports whether the Header field name k is longer than and
+// starts with prefix, ignoring case if fold is set.
+func hasKeyPrefix(k, prefix string, fold bool) bool {
+	if len(k) <= len(prefix) {
+		return false
+	}
+	if fold {
+		return strings.EqualFold(k[:len(prefix)], prefix)
+	}
+	return strings.HasPrefix(k, prefix)
+}
+
+// hasAnyPrefix reports whether the Header field name k starts with any of
+// prefixes, see hasKeyPrefix.
+func hasAnyPrefix(k string, prefixes []string, fold bool) bool {
+	for _, p := range prefixes {
+		if hasKeyPrefix(k, p, fold) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeState holds the state of a single Decode call.
+type decodeState struct {
+	*HeaderDecoder
+	header  http.Header
+	trailer http.Header         // nil to decode the fields with the "trailer" option from header
+	typ     reflect.Type        // struct type passed to Decode
+	missing *MissingHeaderError // required fields whose Header field is missing
+	errs    []error             // errors found with the WithAllErrors option
+
+	// Header fields decoded by the struct fields, see restHeader
+	known    map[string]bool
+	prefixes []string
+}
+
+// parseValue populates the struct fields in val from the header fields.
+// Embedded structs are followed recursively (using the rules defined in the
+// Values function documentation) breadth-first. path is the field path of
+// val in the struct passed to Decode.
+func (d *decodeState) parseValue(val reflect.Value, path string) error {
+	fields, err := d.cachedTypeFields(val.Type())
+	if err != nil {
+		return err
+	}
+	for i := range fields {
+		f := &fields[i]
+		header := d.header
+		if f.trailer && d.trailer != nil {
+			header = d.trailer
+		}
+		key := d.key(f)
+		vals := d.values(header, key)
+		if f.required && !present(header, key, f.kind == kindPrefix, d.foldPrefix()) && len(vals) == 0 {
+			d.addMissing(val, path, f)
+			continue
+		}
+		fromHeader := len(vals) > 0
+		if !fromHeader && f.defaults != nil {
+			vals = f.defaults
+		}
+		raw := vals
+		if fromHeader && f.sep != "" && f.kind != kindValue && !d.singleValue(f) {
+			vals = fieldtag.SplitList(vals, f.sep)
+		}
+		if policy := d.duplicatePolicy(f); policy != DuplicateFirst && d.singleValue(f) {
+			v, ok := duplicateValues(policy, f.opts, vals)
+			if !ok {
+				err := &DuplicateHeaderError{
+					Struct: d.typ.Name(),
+					Field:  fieldPath(val.Type(), path, f.index),
+					Header: f.name,
+					Values: vals,
+				}
+				if err := d.fail(err); err != nil {
 					return err
 				}
 				continue
 			}
+			vals = v
+		}
+		if f.omitEmpty && firstValue(vals) == "" {
+			continue
 		}
 
-		if sv.Kind() == reflect.Ptr {
-			valArr, exist := headerValues(header, name)
-			if !exist {
+		sv := val.FieldByIndex(f.index)
+		switch f.kind {
+		case kindDecoderAddr, kindDecoder:
+			addr := sv
+			if f.kind == kindDecoderAddr {
+				addr = addr.Addr()
+			}
+			if m, ok := addr.Interface().(Decoder); ok {
+				if err := m.DecodeHeader(header, f.name); err != nil {
+					if err := d.fail(d.error(val, path, f, header[key], err)); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+		case kindPtr:
+			if len(vals) == 0 {
 				continue
 			}
 			ve := reflect.New(sv.Type().Elem())
-			if err := fillValues(ve, opts, valArr); err != nil {
-				return err
+			if err := d.fillValues(ve, f.opts, vals); err != nil {
+				if err := d.fail(d.error(val, path, f, raw, err)); err != nil {
+					return err
+				}
+				continue
 			}
 			sv.Set(ve)
 			continue
-		}
-
-		if sv.Type() == timeType {
-			valArr, exist := headerValues(header, name)
-			if !exist {
+		case kindStruct:
+			if err := d.parseValue(sv, fieldPath(val.Type(), path, f.index)); err != nil {
+				return err
+			}
+			continue
+		case kindPrefix:
+			fillPrefix(sv, header, key, d.foldPrefix())
+			continue
+		case kindHeader:
+			if rest := d.restHeader(header); rest != nil {
+				sv.Set(reflect.ValueOf(rest))
+			}
+			continue
+		case kindMulti:
+			if len(vals) == 0 {
 				continue
 			}
-			if err := fillValues(sv, opts, valArr); err != nil {
-				return err
+			if err := d.fillValues(sv, f.opts, vals); err != nil {
+				if err := d.fail(d.error(val, path, f, raw, err)); err != nil {
+					return err
+				}
 			}
 			continue
 		}
 
-		if sv.Kind() == reflect.Struct {
-			if err := parseValue(header, sv); err != nil {
+		if len(vals) == 0 {
+			continue
+		}
+		v := vals[:1]
+
+		if err := d.fillValues(sv, f.opts, v); err != nil {
+			if err := d.fail(d.error(val, path, f, v, err)); err != nil {
 				return err
 			}
 			continue
 		}
 
-		if sv.Kind() != reflect.Slice && sv.Kind() != reflect.Array && sv.Kind() != reflect.Interface {
-			vals, exist := headerValues(header, name)
-			if !exist {
-				continue
-			}
-			v := vals[0]
-			vals = vals[1:]
+		if d.consume && fromHeader {
+			d.consumeValues(header, key, d.unusedValues(f, raw))
+		}
+	}
 
-			if err := fillValues(sv, opts, []string{v}); err != nil {
-				return err
-			}
+	return nil
+}
 
-			header.Del(name)
-			for _, v := range vals {
-				header.Add(name, v)
-			}
+// values returns the values of the Header field key of header, merging the
+// values of the Header fields whose names only differ in case with the
+// KeyFold mode.
+func (d *HeaderDecoder) values(header http.Header, key string) []string {
+	if d.keyMode != KeyFold {
+		return header[key]
+	}
+	keys := foldKeys(header, key)
+	if len(keys) == 1 {
+		return header[keys[0]]
+	}
+	var vals []string
+	for _, k := range keys {
+		vals = append(vals, header[k]...)
+	}
+	return vals
+}
+
+// unusedValues returns vals, the values of the Header field of f, without
+// the one decoded into f, the last one with the "last" DuplicatePolicy and
+// all of them with the "join" one, see DecodeConsume.
+func (d *HeaderDecoder) unusedValues(f *field, vals []string) []string {
+	if d.singleValue(f) {
+		switch d.duplicatePolicy(f) {
+		case DuplicateLast:
+			return vals[:len(vals)-1]
+		case DuplicateJoin:
+			return nil
+		}
+	}
+	return vals[1:]
+}
+
+// consumeValues replaces the values of the Header field key of header with
+// vals, see DecodeConsume.
+func (d *HeaderDecoder) consumeValues(header http.Header, key string, vals []string) {
+	if d.keyMode == KeyFold {
+		for _, k := range foldKeys(header, key) {
+			delete(header, k)
+		}
+	} else {
+		delete(header, key)
+	}
+	if len(vals) > 0 {
+		header[key] = vals
+	}
+}
+
+// foldKeys returns the sorted names of the Header fields of header equal
+// to key ignoring case.
+func foldKeys(header http.Header, key string) []string {
+	var keys []string
+	for k := range header {
+		if strings.EqualFold(k, key) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// knownKey returns the key of the Header field name k in the Header field
+// names collected by knownHeaders, lower case with the KeyFold mode.
+func (d *HeaderDecoder) knownKey(k string) string {
+	if d.keyMode == KeyFold {
+		return strings.ToLower(k)
+	}
+	return k
+}
+
+// foldPrefix reports whether the "prefix" fields match Header field names
+// ignoring case, that is unless with the KeyExact mode.
+func (d *HeaderDecoder) foldPrefix() bool {
+	return d.keyMode != KeyExact
+}
+
+// duplicatePolicy returns the DuplicatePolicy of field f.
+func (d *HeaderDecoder) duplicatePolicy(f *field) DuplicatePolicy {
+	if f.dup != "" {
+		return duplicatePolicies[f.dup]
+	}
+	return d.duplicates
+}
+
+// singleValue reports whether field f is decoded from a single value of its
+// Header field, so that its DuplicatePolicy applies.
+func (d *HeaderDecoder) singleValue(f *field) bool {
+	switch f.kind {
+	case kindValue:
+		return true
+	case kindMulti:
+		return f.typ == timeType
+	case kindPtr:
+		t := indirectType(f.typ)
+		if d.decodeFunc(t) != nil || (t != timeType && implementsText(t, textUnmarshalerType)) {
+			return true
+		}
+		k := t.Kind()
+		return k != reflect.Slice && k != reflect.Array && k != reflect.Interface
+	}
+	return false
+}
+
+// restHeader returns a copy of the Header fields of header that are not
+// decoded by the fields of the struct passed to Decode, or nil if there is
+// none.
+func (d *decodeState) restHeader(header http.Header) http.Header {
+	if d.known == nil {
+		d.known = make(map[string]bool)
+		d.knownHeaders(d.typ, d.known, &d.prefixes)
+	}
+	return restHeader(header, d.known, d.prefixes, d.knownKey, d.foldPrefix())
+}
+
+// restHeader returns a copy of the Header fields of header that are neither
+// known, once passed to knownKey, nor start with one of prefixes, ignoring
+// case if fold is set, or nil if there is none.
+func restHeader(header http.Header, known map[string]bool, prefixes []string, knownKey func(string) string, fold bool) http.Header {
+	var rest http.Header
+	for k, vs := range header {
+		if len(vs) == 0 || known[knownKey(k)] || hasAnyPrefix(k, prefixes, fold) {
 			continue
 		}
+		if rest == nil {
+			rest = make(http.Header)
+		}
+		rest[k] = append([]string(nil), vs...)
+	}
+	return rest
+}
+
+// fail records err and returns nil if the decoder reports all errors, see
+// WithAllErrors, or returns err.
+func (d *decodeState) fail(err error) error {
+	if d.allErrors {
+		d.errs = append(d.errs, err)
+		return nil
+	}
+	return err
+}
+
+// present reports whether the Header field key is present in header, or
+// any Header field whose name starts with key if prefix is set, ignoring
+// case if fold is set.
+func present(header http.Header, key string, prefix, fold bool) bool {
+	if !prefix {
+		return len(header[key]) > 0
+	}
+	for k, vs := range header {
+		if hasKeyPrefix(k, key, fold) && len(vs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// addMissing records the required field f of the struct val as missing.
+func (d *decodeState) addMissing(val reflect.Value, path string, f *field) {
+	if d.missing == nil {
+		d.missing = &MissingHeaderError{Struct: d.typ.Name()}
+	}
+	d.missing.Headers = append(d.missing.Headers, f.name)
+	d.missing.Fields = append(d.missing.Fields, fieldPath(val.Type(), path, f.index))
+}
+
+// error returns a *DecodeError for field f of the struct val.
+func (d *decodeState) error(val reflect.Value, path string, f *field, vals []string, err error) error {
+	return &DecodeError{
+		Struct: d.typ.Name(),
+		Field:  fieldPath(val.Type(), path, f.index),
+		Header: f.name,
+		Value:  strings.Join(vals, ", "),
+		Err:    err,
+	}
+}
+
+// firstValue returns the first of vals, or "" if there is none.
+func firstValue(vals []string) string {
+	if len(vals) == 0 {
+		return ""
+	}
+	return vals[0]
+}
 
-		valArr, exist := headerValues(header, name)
-		if !exist {
+// cloneHeader returns a copy of h.
+func cloneHeader(h http.Header) http.Header {
+	h2 := make(http.Header, len(h))
+	for k, vs := range h {
+		h2[k] = append([]string(nil), vs...)
+	}
+	return h2
+}
+
+// fieldPath returns the dotted path of the field at index in struct type t,
+// appended to path.
+func fieldPath(t reflect.Type, path string, index []int) string {
+	for _, i := range index {
+		sf := t.Field(i)
+		if path != "" {
+			path += "."
+		}
+		path += sf.Name
+		t = sf.Type
+	}
+	return path
+}
+
+// fillPrefix stores the Header fields whose names start with prefix,
+// ignoring case if fold is set, into the map sv, keyed by the rest of their
+// names.
+func fillPrefix(sv reflect.Value, header http.Header, prefix string, fold bool) {
+	multi := sv.Type().Elem().Kind() == reflect.Slice
+	for k, vs := range header {
+		if !hasKeyPrefix(k, prefix, fold) || len(vs) == 0 {
 			continue
 		}
-		if err := fillValues(sv, opts, valArr); err != nil {
-			return err
+		if sv.IsNil() {
+			sv.Set(reflect.MakeMap(sv.Type()))
 		}
+
+		var v reflect.Value
+		if multi {
+			v = reflect.MakeSlice(sv.Type().Elem(), len(vs), len(vs))
+			for i, s := range vs {
+				v.Index(i).SetString(s)
+			}
+		} else {
+			v = reflect.New(sv.Type().Elem()).Elem()
+			v.SetString(vs[0])
+		}
+		mk := reflect.New(sv.Type().Key()).Elem()
+		mk.SetString(k[len(prefix):])
+		sv.SetMapIndex(mk, v)
 	}
+}
 
-	for _, f := range embedded {
-		if err := parseValue(header, f); err != nil {
-			return err
+// parseTime parses value according to the time options in opts, see
+// HeaderEncoder.formatTime.
+func (d *HeaderDecoder) parseTime(value string, opts tagOptions) (time.Time, error) {
+	var unit int64
+	switch {
+	case opts.Contains("unix"):
+		unit = 1e9
+	case opts.Contains("unixmilli"):
+		unit = 1e6
+	case opts.Contains("unixnano"):
+		unit = 1
+	case opts.Contains("rfc3339"):
+		return time.Parse(time.RFC3339, value)
+	}
+	if unit != 0 {
+		u, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, err
 		}
+		per := 1e9 / unit
+		return time.Unix(u/per, u%per*unit).UTC(), nil
+	}
+
+	layout, ok := opts.Value("layout")
+	if !ok {
+		layout = d.timeLayout
 	}
+	if layout == http.TimeFormat {
+		// also accept the obsolete RFC 850 and asctime formats
+		return http.ParseTime(value)
+	}
+	return time.Parse(layout, value)
+}
+
+// parseDuration parses value according to the duration options in opts, see
+// formatDuration. Without any of them, plain integers are accepted as
+// nanoseconds.
+func parseDuration(value string, opts tagOptions) (time.Duration, error) {
+	var unit time.Duration
+	switch {
+	case opts.Contains("seconds"):
+		unit = time.Second
+	case opts.Contains("millis"):
+		unit = time.Millisecond
+	case opts.Contains("godur"):
+		return time.ParseDuration(value)
+	default:
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return time.Duration(n), nil
+		}
+		return time.ParseDuration(value)
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	f *= float64(unit)
+	if math.IsNaN(f) || f >= math.MaxInt64 || f < math.MinInt64 {
+		return 0, fmt.Errorf("httpheader: duration %q out of range", value)
+	}
+	return time.Duration(f), nil
+}
+
+// convertValue sets sv to the value returned by fn for value.
+func convertValue(sv reflect.Value, fn DecodeFunc, value string) error {
+	v, err := fn(value)
+	if err != nil {
+		return err
+	}
+	if !v.IsValid() {
+		return fmt.Errorf("httpheader: DecodeFunc for %s returned an invalid value", sv.Type())
+	}
+	if !v.Type().AssignableTo(sv.Type()) {
+		return fmt.Errorf("httpheader: DecodeFunc for %s returned a %s", sv.Type(), v.Type())
+	}
+	sv.Set(v)
 	return nil
 }
 
-func fillValues(sv reflect.Value, opts tagOptions, valArr []string) error {
+func (d *HeaderDecoder) fillValues(sv reflect.Value, opts tagOptions, valArr []string) error {
 	var err error
 	var value string
 	if len(valArr) > 0 {
 		value = valArr[0]
 	}
-	for sv.Kind() == reflect.Ptr {
+	for {
+		if fn := d.decodeFunc(sv.Type()); fn != nil && sv.CanSet() {
+			return convertValue(sv, fn, value)
+		}
+		if sv.Kind() != reflect.Ptr {
+			break
+		}
+		if sv.IsNil() {
+			sv.Set(reflect.New(sv.Type().Elem()))
+		}
 		sv = sv.Elem()
 	}
 
+	if sv.Type() == durationType {
+		v, err := parseDuration(value, opts)
+		if err != nil {
+			return err
+		}
+		sv.SetInt(int64(v))
+		return nil
+	}
+
+	if sv.Type() != timeType && sv.CanAddr() && reflect.PtrTo(sv.Type()).Implements(textUnmarshalerType) {
+		m := sv.Addr().Interface().(encoding.TextUnmarshaler)
+		return m.UnmarshalText([]byte(value))
+	}
+
 	switch sv.Kind() {
 	case reflect.Bool:
 		var v bool
-		if opts.Contains("int") {
+		if d.strict {
+			if v, err = strconv.ParseBool(value); err != nil {
+				return err
+			}
+		} else if opts.Contains("int") {
 			v = value != "0"
 		} else {
 			v = value != "false"
@@ -244,7 +1146,7 @@ func fillValues(sv reflect.Value, opts tagOptions, valArr []string) error {
 		v := reflect.MakeSlice(sv.Type(), len(valArr), len(valArr))
 		for i, s := range valArr {
 			eleV := reflect.New(sv.Type().Elem()).Elem()
-			if err := fillValues(eleV, opts, []string{s}); err != nil {
+			if err := d.fillValues(eleV, opts, []string{s}); err != nil {
 				return err
 			}
 			v.Index(i).Set(eleV)
@@ -259,7 +1161,7 @@ func fillValues(sv reflect.Value, opts tagOptions, valArr []string) error {
 		}
 		for i := 0; i < length; i++ {
 			eleV := reflect.New(sv.Type().Elem()).Elem()
-			if err := fillValues(eleV, opts, []string{valArr[i]}); err != nil {
+			if err := d.fillValues(eleV, opts, []string{valArr[i]}); err != nil {
 				return err
 			}
 			v.Index(i).Set(eleV)
@@ -273,28 +1175,17 @@ func fillValues(sv reflect.Value, opts tagOptions, valArr []string) error {
 	}
 
 	if sv.Type() == timeType {
-		var v time.Time
-		if opts.Contains("unix") {
-			u, err := strconv.ParseInt(value, 10, 64)
-			if err != nil {
-				return err
-			}
-			v = time.Unix(u, 0).UTC()
-		} else {
-			v, err = time.Parse(http.TimeFormat, value)
-			if err != nil {
-				return err
-			}
+		v, err := d.parseTime(value, opts)
+		if err != nil {
+			return err
 		}
 		sv.Set(reflect.ValueOf(v))
 		return nil
 	}
 
+	if d.strict && sv.Type() != headerType {
+		return &UnsupportedTypeError{sv.Type()}
+	}
 	// sv.Set(reflect.ValueOf(value))
 	return nil
 }
-
-func headerValues(h http.Header, key string) ([]string, bool) {
-	vs, ok := textproto.MIMEHeader(h)[textproto.CanonicalMIMEHeaderKey(key)]
-	return vs, ok
-}
@@ -1,12 +1,16 @@
 package httpheader
 
 import (
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/textproto"
 	"reflect"
 	"regexp"
 	"sort"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -428,7 +432,7 @@ func Test_fillValues_errors(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := fillValues(tt.args.sv, tt.args.opts, tt.args.valArr); (err != nil) != tt.wantErr {
+			if err := defaultDecoder.fillValues(tt.args.sv, tt.args.opts, tt.args.valArr); (err != nil) != tt.wantErr {
 				t.Errorf("fillValues() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -490,6 +494,331 @@ func TestDecode_error(t *testing.T) {
 	}
 }
 
+type failingDecoder struct{}
+
+func (d *failingDecoder) DecodeHeader(header http.Header, key string) error {
+	return fmt.Errorf("failed to decode %s", key)
+}
+
+func TestDecode_DecodeError(t *testing.T) {
+	type Inner struct {
+		Retry int `header:"X-Retry"`
+	}
+	type Outer struct {
+		Inner   Inner
+		Slice   []uint8
+		Decoder failingDecoder `header:"X-Decoder"`
+		B
+	}
+	type Options struct {
+		Outer Outer
+	}
+
+	tests := []struct {
+		in   http.Header
+		want DecodeError
+	}{
+		{
+			http.Header{"X-Retry": []string{"abc"}},
+			DecodeError{Struct: "Options", Field: "Outer.Inner.Retry", Header: "X-Retry", Value: "abc"},
+		},
+		{
+			http.Header{"Slice": []string{"1", "x"}},
+			DecodeError{Struct: "Options", Field: "Outer.Slice", Header: "Slice", Value: "1, x"},
+		},
+		{
+			http.Header{"X-Decoder": []string{"foo"}},
+			DecodeError{Struct: "Options", Field: "Outer.Decoder", Header: "X-Decoder", Value: "foo"},
+		},
+	}
+	for i, tt := range tests {
+		var opt Options
+		err := Decode(tt.in, &opt)
+		e, ok := err.(*DecodeError)
+		if !ok {
+			t.Errorf("%d. Decode returned %#v, want *DecodeError", i, err)
+			continue
+		}
+		if e.Err == nil || e.Unwrap() != e.Err {
+			t.Errorf("%d. DecodeError.Unwrap() = %v, want %v", i, e.Unwrap(), e.Err)
+		}
+		e.Err = nil
+		if !reflect.DeepEqual(tt.want, *e) {
+			t.Errorf("%d. Decode returned %#v, want %#v", i, *e, tt.want)
+		}
+	}
+
+	err := Decode(http.Header{"X-Retry": []string{"abc"}}, &struct{ Outer }{})
+	want := `httpheader: cannot decode header X-Retry value "abc" into field Outer.Inner.Retry: ` +
+		`strconv.ParseInt: parsing "abc": invalid syntax`
+	if err == nil || err.Error() != want {
+		t.Errorf("Decode returned error %v, want %v", err, want)
+	}
+}
+
+func TestDecode_required(t *testing.T) {
+	type Auth struct {
+		Authorization string `header:"Authorization,required"`
+	}
+	type Request struct {
+		TenantID string            `header:"X-Tenant-Id,required"`
+		Retry    *int              `header:"X-Retry,required"`
+		Tags     []string          `header:"X-Tag,required"`
+		Meta     map[string]string `header:"X-Meta-,prefix,required"`
+		Optional string            `header:"X-Optional"`
+		Auth     Auth
+	}
+
+	h := http.Header{
+		"X-Tenant-Id":   []string{"t1"},
+		"X-Retry":       []string{"3"},
+		"X-Tag":         []string{""},
+		"X-Meta-Foo":    []string{"bar"},
+		"Authorization": []string{"token"},
+	}
+	var got Request
+	if err := Decode(h, &got); err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+
+	err := Decode(http.Header{"X-Retry": []string{"3"}}, &got)
+	want := &MissingHeaderError{
+		Struct:  "Request",
+		Headers: []string{"X-Tenant-Id", "X-Tag", "X-Meta-", "Authorization"},
+		Fields:  []string{"TenantID", "Tags", "Meta", "Auth.Authorization"},
+	}
+	if !reflect.DeepEqual(want, err) {
+		t.Errorf("Decode returned %#v, want %#v", err, want)
+	}
+	if want := "httpheader: missing required header: X-Tenant-Id, X-Tag, X-Meta-, Authorization"; err == nil || err.Error() != want {
+		t.Errorf("Decode returned error %v, want %v", err, want)
+	}
+
+	// parse errors are returned before missing headers
+	err = Decode(http.Header{"X-Retry": []string{"x"}}, &got)
+	if _, ok := err.(*DecodeError); !ok {
+		t.Errorf("Decode returned %#v, want *DecodeError", err)
+	}
+}
+
+type defaultStruct struct {
+	Timeout  int       `header:"X-Timeout,default=30"`
+	Retry    *int      `header:"X-Retry,default=3"`
+	Debug    bool      `header:"X-Debug,int,default=1"`
+	Since    time.Time `header:"X-Since,unix,default=946730096"`
+	Encoding []string  `header:"X-Encoding,default=gzip;br"`
+	Ports    [2]int    `header:"X-Ports,default=80;443"`
+	Name     string    `header:"X-Name,omitempty,default=anonymous"`
+	NoDef    string    `header:"X-No-Default"`
+}
+
+func TestDecode_default(t *testing.T) {
+	three := 3
+	want := defaultStruct{
+		Timeout:  30,
+		Retry:    &three,
+		Debug:    true,
+		Since:    time.Date(2000, 1, 1, 12, 34, 56, 0, time.UTC),
+		Encoding: []string{"gzip", "br"},
+		Ports:    [2]int{80, 443},
+		Name:     "anonymous",
+	}
+	var got defaultStruct
+	if err := Decode(http.Header{}, &got); err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want/got:\n%#v\n%#v", want, got)
+	}
+
+	h := http.Header{
+		"X-Timeout":  []string{"5"},
+		"X-Encoding": []string{"identity"},
+		"X-Name":     []string{""},
+	}
+	want = defaultStruct{
+		Timeout:  5,
+		Retry:    &three,
+		Debug:    true,
+		Since:    time.Date(2000, 1, 1, 12, 34, 56, 0, time.UTC),
+		Encoding: []string{"identity"},
+		Ports:    [2]int{80, 443},
+	}
+	got = defaultStruct{}
+	if err := DecodeConsume(h, &got); err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want/got:\n%#v\n%#v", want, got)
+	}
+
+	var sep struct {
+		Encoding []string `header:"X-Encoding,default=gzip|br"`
+	}
+	if err := NewDecoder(WithDefaultSeparator("|")).Decode(http.Header{}, &sep); err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+	if want := []string{"gzip", "br"}; !reflect.DeepEqual(want, sep.Encoding) {
+		t.Errorf("Decode got %#v, want %#v", sep.Encoding, want)
+	}
+
+	var bad struct {
+		Timeout int `header:"X-Timeout,default=soon"`
+	}
+	err := Decode(http.Header{}, &bad)
+	if e, ok := err.(*DecodeError); !ok || e.Value != "soon" {
+		t.Errorf("Decode returned %#v, want *DecodeError for value soon", err)
+	}
+}
+
+func TestDecode_list(t *testing.T) {
+	type listStruct struct {
+		Encoding []string    `header:"Accept-Encoding,comma"`
+		Vary     [2]string   `header:"Vary,comma"`
+		Levels   []textLevel `header:"X-Levels,sep=;"`
+		IfMatch  []string    `header:"If-Match,comma"`
+		Ports    *[]int      `header:"X-Ports,comma"`
+		Default  []string    `header:"X-Default,sep=|,default=a|b"`
+		Scalar   string      `header:"X-Scalar,comma"`
+	}
+	h := http.Header{
+		"Accept-Encoding": []string{"gzip, br", "deflate"},
+		"Vary":            []string{" Accept ,,Origin,Cookie"},
+		"X-Levels":        []string{"low;\thigh"},
+		"If-Match":        []string{`"xyzzy", "r2d2,xxxx", W/"c3\"p,o"`},
+		"X-Ports":         []string{"80,443"},
+		"X-Scalar":        []string{"a, b"},
+	}
+	want := listStruct{
+		Encoding: []string{"gzip", "br", "deflate"},
+		Vary:     [2]string{"Accept", "Origin"},
+		Levels:   []textLevel{0, 1},
+		IfMatch:  []string{`"xyzzy"`, `"r2d2,xxxx"`, `W/"c3\"p,o"`},
+		Ports:    &[]int{80, 443},
+		Default:  []string{"a", "b"},
+		Scalar:   "a, b",
+	}
+	var got listStruct
+	if err := Decode(h, &got); err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want/got:\n%#v\n%#v", want, got)
+	}
+
+	err := Decode(http.Header{"X-Levels": []string{"low;medium"}}, &got)
+	if e, ok := err.(*DecodeError); !ok || e.Value != "low;medium" {
+		t.Errorf("Decode returned %#v, want *DecodeError", err)
+	}
+}
+
+func TestDecode_timeOptions(t *testing.T) {
+	type timeStruct struct {
+		Date      time.Time
+		Unix      time.Time  `header:",unix"`
+		UnixMilli time.Time  `header:",unixmilli"`
+		UnixNano  *time.Time `header:",unixnano"`
+		RFC3339   time.Time  `header:",rfc3339"`
+		Layout    time.Time  `header:",layout=Mon, 02 Jan 2006 15:04"`
+	}
+	tm := time.Date(2000, 1, 1, 12, 34, 56, 789000000, time.UTC)
+	h := http.Header{
+		"Unix":      []string{"946730096"},
+		"Unixmilli": []string{"946730096789"},
+		"Unixnano":  []string{"946730096789000000"},
+		"Rfc3339":   []string{"2000-01-01T20:34:56.789+08:00"},
+		"Layout":    []string{"Sat, 01 Jan 2000 12:34"},
+	}
+	for _, date := range []string{
+		"Sat, 01 Jan 2000 12:34:56 GMT",
+		"Saturday, 01-Jan-00 12:34:56 GMT",
+		"Sat Jan  1 12:34:56 2000",
+	} {
+		h.Set("Date", date)
+		var got timeStruct
+		if err := Decode(h, &got); err != nil {
+			t.Errorf("Decode returned error: %v", err)
+		}
+		if !got.Date.Equal(tm.Truncate(time.Second)) {
+			t.Errorf("Date %q decoded as %v", date, got.Date)
+		}
+		for name, v := range map[string]time.Time{
+			"Unix":      got.Unix,
+			"UnixMilli": got.UnixMilli,
+			"UnixNano":  *got.UnixNano,
+			"RFC3339":   got.RFC3339,
+			"Layout":    got.Layout,
+		} {
+			want := tm
+			switch name {
+			case "Unix":
+				want = tm.Truncate(time.Second)
+			case "Layout":
+				want = tm.Truncate(time.Minute)
+			}
+			if !v.Equal(want) {
+				t.Errorf("%s decoded as %v, want %v", name, v, want)
+			}
+		}
+	}
+
+	var got timeStruct
+	if err := Decode(http.Header{"Rfc3339": []string{"2000-01-01"}}, &got); err == nil {
+		t.Error("expected Decode to return an error")
+	}
+}
+
+func TestDecode_durationOptions(t *testing.T) {
+	type durationStruct struct {
+		Default  time.Duration
+		Nanos    time.Duration
+		GoDur    time.Duration   `header:",godur"`
+		Seconds  time.Duration   `header:",seconds"`
+		Fraction time.Duration   `header:",seconds"`
+		Millis   *time.Duration  `header:",millis"`
+		List     []time.Duration `header:",seconds,comma"`
+	}
+	h := http.Header{
+		"Default":  []string{"1m30s"},
+		"Nanos":    []string{"1000"},
+		"Godur":    []string{"1.5s"},
+		"Seconds":  []string{"120"},
+		"Fraction": []string{"1.5"},
+		"Millis":   []string{"2.5"},
+		"List":     []string{"1, 60"},
+	}
+	var got durationStruct
+	if err := Decode(h, &got); err != nil {
+		t.Fatalf("Decode returned error: %v", err)
+	}
+	millis := 2500 * time.Microsecond
+	want := durationStruct{
+		Default:  90 * time.Second,
+		Nanos:    time.Microsecond,
+		GoDur:    1500 * time.Millisecond,
+		Seconds:  2 * time.Minute,
+		Fraction: 1500 * time.Millisecond,
+		Millis:   &millis,
+		List:     []time.Duration{time.Second, time.Minute},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode returned %+v, want %+v", got, want)
+	}
+
+	for _, h := range []http.Header{
+		{"Godur": []string{"1000"}},
+		{"Seconds": []string{"1m"}},
+		{"Seconds": []string{"1e10"}},
+		{"Seconds": []string{"NaN"}},
+		{"Millis": []string{"-1e20"}},
+	} {
+		var got durationStruct
+		if err := Decode(h, &got); err == nil {
+			t.Errorf("expected Decode(%v) to return an error", h)
+		}
+	}
+}
+
 func TestDecodeHeader_embeddedStructs(t *testing.T) {
 	tests := []struct {
 		in     http.Header
@@ -509,7 +838,7 @@ func TestDecodeHeader_embeddedStructs(t *testing.T) {
 			http.Header{"C": []string{"foo"}},
 			func(h http.Header) (interface{}, error) {
 				var d D
-				err := Decode(h, &d)
+				err := DecodeConsume(h, &d)
 				return d, err
 			},
 			D{B: B{C: ""}, C: "foo"},
@@ -518,7 +847,7 @@ func TestDecodeHeader_embeddedStructs(t *testing.T) {
 			http.Header{"C": []string{"foo", "bar"}},
 			func(h http.Header) (interface{}, error) {
 				var d D
-				err := Decode(h, &d)
+				err := DecodeConsume(h, &d)
 				return d, err
 			},
 			D{B: B{C: "bar"}, C: "foo"},
@@ -527,7 +856,7 @@ func TestDecodeHeader_embeddedStructs(t *testing.T) {
 			http.Header{"C": []string{"foo", "bar"}},
 			func(h http.Header) (interface{}, error) {
 				var f F
-				err := Decode(h, &f)
+				err := DecodeConsume(h, &f)
 				return f, err
 			},
 			F{e{B: B{C: "bar"}, C: "foo"}}, // With unexported embed
@@ -536,11 +865,29 @@ func TestDecodeHeader_embeddedStructs(t *testing.T) {
 			http.Header{"C": []string{"bar"}},
 			func(h http.Header) (interface{}, error) {
 				var f F
-				err := Decode(h, &f)
+				err := DecodeConsume(h, &f)
 				return f, err
 			},
 			F{e{C: "bar"}}, // With unexported embed
 		},
+		{
+			http.Header{"C": []string{"foo", "bar"}},
+			func(h http.Header) (interface{}, error) {
+				var d D
+				err := Decode(h, &d)
+				return d, err
+			},
+			D{B: B{C: "foo"}, C: "foo"},
+		},
+		{
+			http.Header{"C": []string{"bar"}},
+			func(h http.Header) (interface{}, error) {
+				var f F
+				err := Decode(h, &f)
+				return f, err
+			},
+			F{e{B: B{C: "bar"}, C: "bar"}}, // With unexported embed
+		},
 	}
 
 	for i, tt := range tests {
@@ -554,3 +901,400 @@ func TestDecodeHeader_embeddedStructs(t *testing.T) {
 		}
 	}
 }
+
+func TestDecode_doesNotModifyHeader(t *testing.T) {
+	type consumeStruct struct {
+		A     string
+		B     string `header:"A"`
+		Slice []string
+		D
+	}
+	for _, decode := range []func(http.Header, interface{}) error{Decode, DecodeConsume} {
+		h := http.Header{
+			"A":     []string{"a1", "a2"},
+			"C":     []string{"foo", "bar"},
+			"Slice": []string{"x"},
+		}
+		want := cloneHeader(h)
+		var got consumeStruct
+		if err := decode(h, &got); err != nil {
+			t.Errorf("decode returned error: %v", err)
+		}
+		if !reflect.DeepEqual(want, h) {
+			t.Errorf("decode modified header: got %v, want %v", h, want)
+		}
+	}
+}
+
+func TestDecodeHeader_TextUnmarshaler(t *testing.T) {
+	type textStruct struct {
+		A textLevel
+		B *textLevel
+		C []textLevel
+		D net.IP
+		E []net.IP
+		F *net.IP
+	}
+	h := http.Header{
+		"A": []string{"high"},
+		"B": []string{"high"},
+		"C": []string{"low", "high"},
+		"D": []string{"192.168.0.1"},
+		"E": []string{"10.0.0.1", "::1"},
+		"F": []string{"::1"},
+	}
+	high := textLevel(1)
+	ip := net.ParseIP("::1")
+	want := textStruct{
+		A: 1,
+		B: &high,
+		C: []textLevel{0, 1},
+		D: net.ParseIP("192.168.0.1"),
+		E: []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("::1")},
+		F: &ip,
+	}
+	var got textStruct
+	if err := Decode(h, &got); err != nil {
+		t.Errorf("Decode returned error: %#v", err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want/got:\n%#v\n%#v", want, got)
+	}
+
+	err := Decode(http.Header{"C": []string{"low", "medium"}}, &got)
+	if err == nil {
+		t.Error("expected Decode() to return the UnmarshalText error")
+	}
+}
+
+func TestDecodeHeader_prefix(t *testing.T) {
+	type prefixStruct struct {
+		Meta  map[string]string   `header:"X-Cos-Meta-,prefix"`
+		Multi map[string][]string `header:"x-amz-meta-,prefix"`
+		Empty map[string]string   `header:"X-Empty-,prefix"`
+		Keep  map[string]string   `header:"X-Keep-,prefix"`
+	}
+	h := http.Header{
+		"X-Cos-Meta-Foo":         []string{"bar", "baz"},
+		"X-Cos-Meta-Hello-World": []string{"1"},
+		"X-Cos-Meta-":            []string{"no key"},
+		"X-Amz-Meta-A":           []string{"1", "2"},
+		"X-Keep-B":               []string{"b"},
+		"X-Other":                []string{"other"},
+	}
+	want := prefixStruct{
+		Meta:  map[string]string{"Foo": "bar", "Hello-World": "1"},
+		Multi: map[string][]string{"A": {"1", "2"}},
+		Keep:  map[string]string{"a": "a", "B": "b"},
+	}
+	got := prefixStruct{Keep: map[string]string{"a": "a"}}
+	if err := Decode(h, &got); err != nil {
+		t.Errorf("Decode returned error: %#v", err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want/got:\n%#v\n%#v", want, got)
+	}
+	if got.Empty != nil {
+		t.Errorf("Empty should be nil, got %#v", got.Empty)
+	}
+}
+
+func TestDecodeHeader_header(t *testing.T) {
+	type Inner struct {
+		Retry int `header:"X-Retry"`
+	}
+	type headerStruct struct {
+		Inner
+		Name  string            `header:"X-Name"`
+		Meta  map[string]string `header:"X-Meta-,prefix"`
+		Amz   http.Header       `header:"X-Amz-,prefix"`
+		Rest  http.Header
+		Empty http.Header `header:",omitempty"`
+	}
+	h := http.Header{
+		"X-Name":       []string{"foo"},
+		"X-Retry":      []string{"3"},
+		"X-Meta-A":     []string{"a"},
+		"X-Amz-Id":     []string{"1", "2"},
+		"X-Other":      []string{"b", "c"},
+		"content-type": []string{"text/plain"},
+		"X-Nil":        nil,
+	}
+	want := headerStruct{
+		Inner: Inner{Retry: 3},
+		Name:  "foo",
+		Meta:  map[string]string{"A": "a"},
+		Amz:   http.Header{"Id": {"1", "2"}},
+		Rest:  http.Header{"X-Other": {"b", "c"}, "content-type": {"text/plain"}},
+		Empty: http.Header{"X-Other": {"b", "c"}, "content-type": {"text/plain"}},
+	}
+	var got headerStruct
+	if err := Decode(h, &got); err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want/got:\n%#v\n%#v", want, got)
+	}
+	got.Rest["X-Other"][0] = "changed"
+	if h["X-Other"][0] != "b" {
+		t.Error("Decode shares the values of header with the http.Header field")
+	}
+
+	// the decoded fields encode to the same Header fields
+	want.Empty = nil
+	enc, err := Header(want)
+	if err != nil {
+		t.Fatalf("Header returned error: %v", err)
+	}
+	var rt headerStruct
+	if err := Decode(enc, &rt); err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+	rt.Empty = nil
+	want.Rest = http.Header{"X-Other": {"b", "c"}, "Content-Type": {"text/plain"}}
+	if !reflect.DeepEqual(want, rt) {
+		t.Errorf("round trip want/got:\n%#v\n%#v", want, rt)
+	}
+
+	// nothing is left for the http.Header fields
+	got = headerStruct{}
+	if err := Decode(http.Header{"X-Name": {"foo"}}, &got); err != nil || got.Rest != nil {
+		t.Errorf("Decode returned %v, Rest = %#v, want nil", err, got.Rest)
+	}
+
+	// the Header field gating an omitempty struct is not left over
+	var gated struct {
+		Inner Inner `header:"X-Inner,omitempty"`
+		Rest  http.Header
+	}
+	err = Decode(http.Header{"X-Inner": {"1"}, "X-Retry": {"3"}}, &gated)
+	if err != nil || gated.Inner.Retry != 3 || gated.Rest != nil {
+		t.Errorf("Decode returned %+v, %v, want Retry 3 and nil Rest", gated, err)
+	}
+
+	// with an http.Header field, no Header field is unknown
+	err = NewDecoder(WithDisallowUnknownHeaders()).Decode(h, &headerStruct{})
+	if err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+}
+
+func TestDecodeHeader_map(t *testing.T) {
+	h := http.Header{
+		"X-Name":  {"foo", "bar"},
+		"x-name":  {"baz"},
+		"X-Count": {"1"},
+		"X-Empty": {},
+	}
+
+	var lower map[string]string
+	if err := NewDecoder(WithKeyCanonicalizer(strings.ToLower)).Decode(h, &lower); err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+	if want := map[string]string{"x-name": "foo", "x-count": "1"}; !reflect.DeepEqual(lower, want) {
+		t.Errorf("Decode returned %v, want %v", lower, want)
+	}
+
+	all := http.Header{"Existing": {"value"}}
+	if err := Decode(h, &all); err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+	want := http.Header{"Existing": {"value"}, "X-Name": {"foo", "bar", "baz"}, "X-Count": {"1"}}
+	if !reflect.DeepEqual(all, want) {
+		t.Errorf("Decode returned %v, want %v", all, want)
+	}
+
+	var counts map[string]int
+	err := Decode(h, &counts)
+	e, ok := err.(*DecodeError)
+	if !ok || e.Field != "X-Name" || e.Value != "foo, bar, baz" {
+		t.Errorf("Decode returned error %#v, want *DecodeError for X-Name", err)
+	}
+	err = NewDecoder(WithAllErrors()).Decode(h, &counts)
+	if errs, ok := err.(*DecodeErrors); !ok || len(errs.Errors) != 1 || counts["X-Count"] != 1 {
+		t.Errorf("Decode returned error %v and %v, want one error and X-Count", err, counts)
+	}
+
+	if err := Decode(h, &map[int]string{}); err == nil {
+		t.Error("Decode into map[int]string returned no error")
+	}
+}
+
+func TestDecodeHeader_filter(t *testing.T) {
+	h := http.Header{"X-Name": {"foo"}, "Authorization": {"secret"}, "X-Count": {"1"}}
+	d := NewDecoder(WithHeaderFilter(func(name string) bool {
+		return name != "Authorization"
+	}))
+
+	var m map[string][]string
+	if err := d.Decode(h, &m); err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+	if want := map[string][]string{"X-Name": {"foo"}, "X-Count": {"1"}}; !reflect.DeepEqual(m, want) {
+		t.Errorf("Decode returned %v, want %v", m, want)
+	}
+
+	var s struct {
+		Name string `header:"X-Name"`
+		Auth string `header:"Authorization,default=none"`
+		Rest http.Header
+	}
+	if err := d.Decode(h, &s); err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+	if s.Name != "foo" || s.Auth != "none" || !reflect.DeepEqual(s.Rest, http.Header{"X-Count": {"1"}}) {
+		t.Errorf("Decode returned %+v", s)
+	}
+}
+
+func TestDecodeHeader_duplicates(t *testing.T) {
+	type dupStruct struct {
+		First  string     `header:"X-A"`
+		Last   int        `header:"X-B,dup=last"`
+		Join   string     `header:"X-C,dup=join"`
+		Sep    *string    `header:"X-C,dup=join,sep=;"`
+		Time   time.Time  `header:"X-D,unix,dup=last"`
+		List   []string   `header:"X-A,dup=unique"`
+		Level  *textLevel `header:"X-E,dup=last"`
+		Unique string     `header:"X-F,dup=unique"`
+		S      string     `header:"X-S,dup=last,sep=;"`
+		P      *string    `header:"X-S,dup=last,sep=;"`
+	}
+	h := http.Header{
+		"X-A": {"a", "b"},
+		"X-B": {"1", "2"},
+		"X-C": {"a", "b"},
+		"X-D": {"1", "2"},
+		"X-E": {"low", "high"},
+		"X-F": {"f"},
+		"X-S": {"a;b"},
+	}
+	high := textLevel(1)
+	want := dupStruct{
+		First:  "a",
+		Last:   2,
+		Join:   "a, b",
+		Sep:    stringPoint("a;b"),
+		Time:   time.Unix(2, 0).UTC(),
+		List:   []string{"a", "b"},
+		Level:  &high,
+		Unique: "f",
+		S:      "a;b",
+		P:      stringPoint("a;b"),
+	}
+	var got dupStruct
+	if err := Decode(h, &got); err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want/got:\n%#v\n%#v", want, got)
+	}
+
+	h["X-F"] = []string{"f", "g"}
+	err := Decode(h, &got)
+	wantErr := &DuplicateHeaderError{Struct: "dupStruct", Field: "Unique", Header: "X-F", Values: []string{"f", "g"}}
+	if !reflect.DeepEqual(err, wantErr) {
+		t.Errorf("Decode returned error %#v, want %#v", err, wantErr)
+	}
+	if msg := "httpheader: header X-F has 2 values, field dupStruct.Unique allows only one"; err.Error() != msg {
+		t.Errorf("Error() = %q, want %q", err.Error(), msg)
+	}
+
+	// the values reported are those of the Header field, not split
+	var p struct {
+		P *string `header:"X-P,dup=unique,sep=;"`
+	}
+	err = Decode(http.Header{"X-P": {"a;b", "c"}}, &p)
+	if e, ok := err.(*DuplicateHeaderError); !ok || !reflect.DeepEqual(e.Values, []string{"a;b", "c"}) {
+		t.Errorf("Decode returned error %#v, want Values [a;b c]", err)
+	}
+	if err := Decode(http.Header{"X-P": {"a;b"}}, &p); err != nil || *p.P != "a;b" {
+		t.Errorf("Decode returned %v, error %v, want a;b", p.P, err)
+	}
+}
+
+func TestDecodeHeader_raw(t *testing.T) {
+	type rawStruct struct {
+		A    string            `header:"x-a,raw,required"`
+		B    string            `header:"x-b"`
+		Meta map[string]string `header:"x-cos-meta-,prefix,raw"`
+	}
+	h := http.Header{"x-a": {"a"}, "X-A": {"other"}, "X-B": {"b"}, "x-cos-meta-MyKey": {"v"}}
+	want := rawStruct{A: "a", B: "b", Meta: map[string]string{"MyKey": "v"}}
+	var got rawStruct
+	if err := Decode(h, &got); err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want/got:\n%#v\n%#v", want, got)
+	}
+
+	err := Decode(http.Header{"X-A": {"a"}}, &rawStruct{})
+	if _, ok := err.(*MissingHeaderError); !ok {
+		t.Errorf("Decode returned error %v, want *MissingHeaderError", err)
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		h := getHeader(SyncEvent)
+		b.StartTimer()
+		var p GoogleCalendarPayload
+		if err := Decode(h, &p); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestDecodeHeader_sliceOfPointers(t *testing.T) {
+	type ptrSlice struct {
+		A []*string
+		B []*int
+	}
+	h := http.Header{"A": []string{"a", "b"}, "B": []string{"1"}}
+	one := 1
+	want := ptrSlice{A: []*string{stringPoint("a"), stringPoint("b")}, B: []*int{&one}}
+	var got ptrSlice
+	if err := Decode(h, &got); err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want/got:\n%#v\n%#v", want, got)
+	}
+}
+
+func TestDecodeErrors_unwrap(t *testing.T) {
+	type unwrapStruct struct {
+		Name  string `header:"X-Name,required"`
+		Count int    `header:"X-Count"`
+	}
+	err := NewDecoder(WithAllErrors()).Decode(http.Header{"X-Count": {"a"}}, &unwrapStruct{})
+
+	var missing *MissingHeaderError
+	if !errors.As(err, &missing) || missing.Headers[0] != "X-Name" {
+		t.Errorf("errors.As(%v, *MissingHeaderError) = false", err)
+	}
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Header != "X-Count" {
+		t.Errorf("errors.As(%v, *DecodeError) = false", err)
+	}
+	if !errors.Is(err, strconv.ErrSyntax) {
+		t.Errorf("errors.Is(%v, strconv.ErrSyntax) = false", err)
+	}
+
+	// the Is and As methods match the errors without Unwrap, before Go 1.20
+	errs := err.(*DecodeErrors)
+	if !errs.Is(strconv.ErrSyntax) || errs.Is(errors.New("other")) {
+		t.Errorf("Is(%v) doesn't match strconv.ErrSyntax only", err)
+	}
+	missing = nil
+	if !errs.As(&missing) || missing.Headers[0] != "X-Name" {
+		t.Errorf("As(%v, *MissingHeaderError) = false", err)
+	}
+	var unknown *UnknownHeaderError
+	if errs.As(&unknown) {
+		t.Errorf("As(%v, *UnknownHeaderError) = true", err)
+	}
+}
@@ -18,12 +18,16 @@
 package httpheader
 
 import (
+	"encoding"
+	"errors"
 	"fmt"
 	"net/http"
 	"reflect"
+	"sort"
 	"strconv"
-	"strings"
 	"time"
+
+	"github.com/mozillazg/go-httpheader/internal/fieldtag"
 )
 
 const tagName = "header"
@@ -32,12 +36,16 @@ const tagName = "header"
 const Version = "0.3.1"
 
 var timeType = reflect.TypeOf(time.Time{})
+var durationType = reflect.TypeOf(time.Duration(0))
 var headerType = reflect.TypeOf(http.Header{})
 
 var encoderType = reflect.TypeOf(new(Encoder)).Elem()
+var textMarshalerType = reflect.TypeOf(new(encoding.TextMarshaler)).Elem()
 
 // Encoder is an interface implemented by any type that wishes to encode
-// itself into Header fields in a non-standard way.
+// itself into Header fields in a non-standard way. EncodeHeader is passed
+// the Header being encoded, whose fields it may add, set or delete. The
+// Header fields it adds or changes are validated like the other fields.
 type Encoder interface {
 	EncodeHeader(key string, v *http.Header) error
 }
@@ -75,6 +83,15 @@ type Encoder interface {
 // 	// is skipped if empty.  Note the leading comma.
 // 	Field int `header:",omitempty"`
 //
+// 	// Field is omitted by a HeaderEncoder created with the WithOmitDefault
+// 	// option if its value is 30, see Decode for the "default" option.
+// 	Field int `header:"X-Name,default=30"`
+//
+// 	// Field appears as trailer field "X-Checksum" when encoded by
+// 	// EncodeToRequest, EncodeToResponse or EncodeToResponseWriter, and as
+// 	// Header field "X-Checksum" otherwise.
+// 	Field string `header:"X-Checksum,trailer"`
+//
 // For encoding individual field values, the following type-dependent rules
 // apply:
 //
@@ -84,14 +101,70 @@ type Encoder interface {
 //
 // time.Time values default to encoding as RFC1123("Mon, 02 Jan 2006 15:04:05 GMT")
 // timestamps. Including the "unix" option signals that the field should be
-// encoded as a Unix time (see time.Unix())
+// encoded as a Unix time (see time.Unix()). The following options are also
+// available:
+//
+//	- "unixmilli": milliseconds since the Unix epoch
+//	- "unixnano": nanoseconds since the Unix epoch
+//	- "rfc3339": RFC 3339 timestamp with fractional seconds, if any
+//	- "layout=<layout>": the layout given, see time.Format. It must be the
+//	  last option of the tag, as it may contain commas.
+//
+// For example:
+//
+//	// Field appears as Header field "X-Request-Time: 2006-01-02T15:04:05.999Z".
+//	Field time.Time `header:"X-Request-Time,rfc3339"`
+//
+//	// Field appears as Header field "X-Date: Mon, 02 Jan 2006".
+//	Field time.Time `header:"X-Date,layout=Mon, 02 Jan 2006"`
+//
+// time.Duration values default to encoding as Go durations such as "1m30s"
+// (see time.Duration.String()), which the "godur" option also selects.
+// Including the "seconds" or "millis" option signals that the field should be
+// encoded as a number of seconds or milliseconds, with a fractional part if
+// needed. For example:
+//
+//	// Field 2*time.Minute appears as Header field "Retry-After: 120".
+//	Field time.Duration `header:"Retry-After,seconds"`
+//
+// Values implementing encoding.TextMarshaler are encoded as the result of
+// their MarshalText method. This applies to slice and array elements too.
 //
 // Slice and Array values default to encoding as multiple Header values of the
 // same name. example:
 // X-Name: []string{"Tom", "Jim"}, etc.
+// Including the "comma" option signals that the values should be joined by
+// ", " into a single Header value, and the "sep=<separator>" option joins
+// them by the given separator. example:
+//
+//	// Field []string{"gzip", "br"} appears as "Accept-Encoding: gzip, br".
+//	Field []string `header:"Accept-Encoding,comma"`
+//
+//	// Field []string{"a", "b"} appears as "X-List: a;b".
+//	Field []string `header:"X-List,sep=;"`
+//
+// Header returns an *InvalidListElementError for an element that wouldn't
+// decode back as is: one with leading or trailing whitespace, holding the
+// separator outside a quoted string, or an unterminated quoted string.
 //
 // http.Header values will be used to extend the Header fields.
 //
+// map[string]string and map[string][]string values with the "prefix" option
+// are encoded as one Header field per map key, named by the field name
+// followed by the key. example:
+//
+//	// Meta{"Foo": "bar"} appears as Header field "X-Cos-Meta-Foo: bar".
+//	Meta map[string]string `header:"X-Cos-Meta-,prefix"`
+//
+// Header field names are canonicalized, see http.CanonicalHeaderKey, unless
+// the field has the "raw" option, which writes them verbatim, including the
+// names of "prefix" maps and http.Header values. For example:
+//
+//	// Meta{"MyKey": "v"} appears as Header field "x-cos-meta-MyKey: v".
+//	Meta map[string]string `header:"x-cos-meta-,prefix,raw"`
+//
+// See WithKeyMode to write every name verbatim.
+//
 // Anonymous struct fields are usually encoded as if their inner exported
 // fields were fields in the outer struct, subject to the standard Go
 // visibility rules. An anonymous struct field with a name given in its Header
@@ -103,146 +176,569 @@ type Encoder interface {
 //
 // Multiple fields that encode to the same Header filed name will be included
 // as multiple Header values of the same name.
+//
+// Header also accepts a map with string keys, such as an http.Header, whose
+// entries are encoded as Header fields named by their keys, canonicalized, in
+// key order. Their values are encoded like struct fields without options,
+// slices and arrays as multiple Header values, and nil interface values as
+// "" like nil pointers.
+//
+// Header field names must be tokens and values must not hold control
+// characters other than horizontal tab, so that they can't be used to inject
+// Header fields. Header returns an *InvalidHeaderError for the first field
+// that doesn't comply, see WithSanitize for alternatives.
+//
+// If v is a struct whose methods are generated by the httpheader-gen command,
+// its EncodeHeader method is called instead of using reflection. Other
+// structs implementing Encoder are encoded by reflection, the method only
+// being used for the fields of their type. Fields of generated struct types
+// are encoded by reflection like plain structs.
 func Header(v interface{}) (http.Header, error) {
+	if m, ok := structEncoder(v); ok {
+		h := make(http.Header)
+		err := m.EncodeHeader("", &h)
+		return h, err
+	}
+	return defaultEncoder.Encode(v)
+}
+
+// HeaderEncoder encodes structs into http.Header fields with a set of
+// options. It is safe for concurrent use by multiple goroutines.
+type HeaderEncoder struct {
+	config
+}
+
+var defaultEncoder = NewEncoder()
+
+// NewEncoder returns a HeaderEncoder configured by opts.
+func NewEncoder(opts ...Option) *HeaderEncoder {
+	e := new(HeaderEncoder)
+	e.init(e.encodeKind, opts)
+	return e
+}
+
+// Encode returns the http.Header encoding of v, using the rules described in
+// the documentation for the Header function.
+func (e *HeaderEncoder) Encode(v interface{}) (http.Header, error) {
+	return e.encode(reflect.ValueOf(v))
+}
+
+// EncodeInto merges the encoding of v into dst according to mode, using the
+// rules described in the documentation for the Header function. The fields
+// encoded before an error is returned are left in dst.
+//
+// EncodeInto can be used to layer Header fields, e.g. default fields with
+// MergeKeepExisting over per-request fields encoded with MergeSet.
+func EncodeInto(dst http.Header, v interface{}, mode MergeMode) error {
+	return defaultEncoder.EncodeInto(dst, v, mode)
+}
+
+// EncodeInto merges the encoding of v into dst, see the EncodeInto function.
+func (e *HeaderEncoder) EncodeInto(dst http.Header, v interface{}, mode MergeMode) error {
+	if dst == nil {
+		return errors.New("httpheader: EncodeInto: nil dst")
+	}
+	return e.encodeInto(reflect.ValueOf(v), &headerSink{dst: &dst, mode: mode}, nil)
+}
+
+// encode returns the http.Header encoding of the struct val, following
+// pointers and interfaces.
+func (e *HeaderEncoder) encode(val reflect.Value) (http.Header, error) {
+	val, err := structValue(val)
+	if err != nil {
+		return nil, err
+	}
 	h := make(http.Header)
-	val := reflect.ValueOf(v)
-	for val.Kind() == reflect.Ptr {
+	if val.IsValid() {
+		es := encodeState{HeaderEncoder: e, header: &headerSink{dst: &h}, typ: val.Type()}
+		err = es.value(val)
+	}
+	return h, err
+}
+
+// encodeInto merges the encoding of the struct val into header, and the
+// encoding of the fields with the "trailer" option into trailer if it is not
+// nil.
+func (e *HeaderEncoder) encodeInto(val reflect.Value, header, trailer *headerSink) error {
+	val, err := structValue(val)
+	if err != nil || !val.IsValid() {
+		return err
+	}
+	es := encodeState{HeaderEncoder: e, header: header, trailer: trailer, typ: val.Type()}
+	return es.value(val)
+}
+
+// structValue follows the pointers and interfaces in val to a struct or a
+// map with string keys. It returns the zero Value if val is, or leads to, a
+// nil value.
+func structValue(val reflect.Value) (reflect.Value, error) {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
 		if val.IsNil() {
-			return h, nil
+			return reflect.Value{}, nil
 		}
 		val = val.Elem()
 	}
-
-	if v == nil {
-		return h, nil
+	if !val.IsValid() || val.Kind() == reflect.Struct {
+		return val, nil
 	}
-
-	if val.Kind() != reflect.Struct {
-		return nil, fmt.Errorf("httpheader: Header() expects struct input. Got %v", val.Kind())
+	if val.Kind() == reflect.Map && val.Type().Key().Kind() == reflect.String {
+		return val, nil
 	}
+	return val, fmt.Errorf("httpheader: Header() expects struct input. Got %v", val.Kind())
+}
 
-	err := reflectValue(h, val)
-	return h, err
+// encodeState holds the state of a single Encode call.
+type encodeState struct {
+	*HeaderEncoder
+	header  *headerSink
+	trailer *headerSink  // nil to encode the fields with the "trailer" option into header
+	typ     reflect.Type // struct type passed to Encode
 }
 
-// reflectValue populates the header fields from the struct fields in val.
-// Embedded structs are followed recursively (using the rules defined in the
-// Values function documentation) breadth-first.
-func reflectValue(header http.Header, val reflect.Value) error {
-	var embedded []reflect.Value
-
-	typ := val.Type()
-	for i := 0; i < typ.NumField(); i++ {
-		sf := typ.Field(i)
-		if sf.PkgPath != "" && !sf.Anonymous { // unexported
+// value populates the header fields from the struct or the map val.
+func (e *encodeState) value(val reflect.Value) error {
+	if val.Kind() == reflect.Map {
+		return e.mapValue(val)
+	}
+	return e.reflectValue(val, "")
+}
+
+// mapValue populates the header fields from the entries of the map val,
+// whose keys are the Header field names, in key order.
+func (e *encodeState) mapValue(val reflect.Value) error {
+	keys := val.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	f := new(field) // the entries have no tag options
+	for _, mk := range keys {
+		k := mk.String()
+		name := e.canonicalKey(k)
+		v := val.MapIndex(mk)
+		for v.Kind() == reflect.Interface && !v.IsNil() {
+			v = v.Elem()
+		}
+		if v.Kind() == reflect.Interface {
+			// nil interface value, encoded like a nil pointer
+			if err := e.add(val, k, f, name, ""); err != nil {
+				return err
+			}
 			continue
 		}
-
-		sv := val.Field(i)
-		tag := sf.Tag.Get(tagName)
-		if tag == "-" {
+		t := v.Type()
+		if (t.Kind() != reflect.Slice && t.Kind() != reflect.Array) ||
+			e.hasEncodeFunc(t) || implementsText(t, textMarshalerType) {
+			s, err := e.valueString(v, nil)
+			if err != nil {
+				return err
+			}
+			if err := e.add(val, k, f, name, s); err != nil {
+				return err
+			}
 			continue
 		}
-		name, opts := parseTag(tag)
-		if name == "" {
-			if sf.Anonymous && sv.Kind() == reflect.Struct {
-				// save embedded struct for later processing
-				embedded = append(embedded, sv)
-				continue
+		for i := 0; i < v.Len(); i++ {
+			s, err := e.valueString(v.Index(i), nil)
+			if err != nil {
+				return err
+			}
+			if err := e.add(val, k, f, name, s); err != nil {
+				return err
 			}
-
-			name = sf.Name
 		}
+	}
+	return nil
+}
 
-		if opts.Contains("omitempty") && isEmptyValue(sv) {
+// reflectValue populates the header fields from the struct fields in val.
+// Embedded structs are followed recursively (using the rules defined in the
+// Values function documentation) breadth-first. path is the field path of
+// val in the struct passed to Encode.
+func (e *encodeState) reflectValue(val reflect.Value, path string) error {
+	fields, err := e.cachedTypeFields(val.Type())
+	if err != nil {
+		return err
+	}
+	for i := range fields {
+		f := &fields[i]
+		sv := val.FieldByIndex(f.index)
+		if f.omitEmpty && isEmptyValue(sv) {
 			continue
 		}
+		if e.omitDefault && f.defaults != nil {
+			def, err := e.isDefault(sv, f)
+			if err != nil {
+				return err
+			}
+			if def {
+				continue
+			}
+		}
 
-		if sv.Type().Implements(encoderType) {
+		switch f.kind {
+		case kindEncoder:
 			if !reflect.Indirect(sv).IsValid() {
 				sv = reflect.New(sv.Type().Elem())
 			}
 
-			m := sv.Interface().(Encoder)
-			if err := m.EncodeHeader(name, &header); err != nil {
+			if err := e.encoder(val, path, f, sv.Interface().(Encoder)); err != nil {
 				return err
 			}
 			continue
-		}
-
-		if sv.Kind() == reflect.Slice || sv.Kind() == reflect.Array {
+		case kindSlice:
+			key := e.key(f)
+			if f.sep != "" {
+				if sv.Len() == 0 {
+					continue
+				}
+				s, err := e.joinValues(val, path, f, key, sv)
+				if err != nil {
+					return err
+				}
+				if err := e.add(val, path, f, key, s); err != nil {
+					return err
+				}
+				continue
+			}
 			for i := 0; i < sv.Len(); i++ {
-				k := name
-				header.Add(k, valueString(sv.Index(i), opts))
+				s, err := e.valueString(sv.Index(i), f.opts)
+				if err != nil {
+					return err
+				}
+				if err := e.add(val, path, f, key, s); err != nil {
+					return err
+				}
 			}
 			continue
-		}
-
-		for sv.Kind() == reflect.Ptr {
-			if sv.IsNil() {
-				break
+		case kindHeader:
+			if sv = indirect(sv); sv.Kind() == reflect.Ptr {
+				break // nil pointer
+			}
+			if err := e.addHeader(val, path, f, sv.Interface().(http.Header), true); err != nil {
+				return err
 			}
-			sv = sv.Elem()
-		}
-
-		if sv.Type() == timeType {
-			header.Add(name, valueString(sv, opts))
 			continue
-		}
-		if sv.Type() == headerType {
-			h := sv.Interface().(http.Header)
-			for k, vs := range h {
-				for _, v := range vs {
-					header.Add(k, v)
+		case kindStruct:
+			if sv = indirect(sv); sv.Kind() == reflect.Ptr {
+				break // nil pointer
+			}
+			if err := e.reflectValue(sv, fieldPath(val.Type(), path, f.index)); err != nil {
+				return err
+			}
+			continue
+		case kindPrefix:
+			for _, mk := range sv.MapKeys() {
+				k := e.fieldKey(f, f.name+mk.String())
+				v := sv.MapIndex(mk)
+				if v.Kind() != reflect.Slice {
+					if err := e.add(val, path, f, k, v.String()); err != nil {
+						return err
+					}
+					continue
+				}
+				for i := 0; i < v.Len(); i++ {
+					if err := e.add(val, path, f, k, v.Index(i).String()); err != nil {
+						return err
+					}
 				}
 			}
 			continue
 		}
 
-		if sv.Kind() == reflect.Struct {
-			if err := reflectValue(header, sv); err != nil {
+		s, err := e.valueString(sv, f.opts)
+		if err != nil {
+			return err
+		}
+		if err := e.add(val, path, f, e.key(f), s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// add appends value to the values of the Header field name, encoded from
+// field f of the struct val. Invalid names and values are sanitized or
+// reported as an *InvalidHeaderError, see WithSanitize.
+func (e *encodeState) add(val reflect.Value, path string, f *field, name, value string) error {
+	if validHeaderName(name) && validHeaderValue(value) {
+		e.put(f, name, value)
+		return nil
+	}
+	if e.sanitize != SanitizeReject {
+		k := name
+		if !validHeaderName(k) {
+			k = e.fieldKey(f, sanitizeHeaderName(k, e.sanitize))
+		}
+		if k != "" {
+			e.put(f, k, sanitizeHeaderValue(value, e.sanitize))
+			return nil
+		}
+	}
+	err := &InvalidHeaderError{
+		Struct: e.typ.Name(),
+		Field:  fieldPath(val.Type(), path, f.index),
+		Header: name,
+	}
+	if validHeaderName(name) {
+		err.Value = value
+	}
+	return err
+}
+
+// put adds value to the Header field name, or to the trailer field name if
+// f has the "trailer" option.
+func (e *encodeState) put(f *field, name, value string) {
+	e.sink(f).put(name, value)
+}
+
+// sink returns the headerSink field f is encoded into.
+func (e *encodeState) sink(f *field) *headerSink {
+	if f.trailer && e.trailer != nil {
+		return e.trailer
+	}
+	return e.header
+}
+
+// encoder calls the EncodeHeader method of m, the value of field f of the
+// struct val. The method is passed the Header being encoded, unless it is
+// merged with a MergeMode or a prefix, and the Header fields it adds or
+// changes are validated like the other fields.
+func (e *encodeState) encoder(val reflect.Value, path string, f *field, m Encoder) error {
+	sink := e.sink(f)
+	if sink.mode != MergeAdd || sink.prefix != "" {
+		h := make(http.Header)
+		if err := m.EncodeHeader(f.name, &h); err != nil {
+			return err
+		}
+		return e.addHeader(val, path, f, h, false)
+	}
+
+	if *sink.dst == nil {
+		*sink.dst = make(http.Header)
+	}
+	changed, err := encodeHeader(m, f.name, sink.dst)
+	if err != nil {
+		return err
+	}
+	for _, k := range changed {
+		vs := (*sink.dst)[k]
+		delete(*sink.dst, k)
+		for _, v := range vs {
+			if err := e.add(val, path, f, k, v); err != nil {
 				return err
 			}
-			continue
 		}
+	}
+	return nil
+}
 
-		header.Add(name, valueString(sv, opts))
+// encodeHeader calls the EncodeHeader method of m with the Header dst, and
+// returns the sorted names of the Header fields it added or changed.
+func encodeHeader(m Encoder, key string, dst *http.Header) ([]string, error) {
+	before := make(http.Header, len(*dst))
+	for k, vs := range *dst {
+		before[k] = append([]string(nil), vs...)
+	}
+	if err := m.EncodeHeader(key, dst); err != nil {
+		return nil, err
 	}
+	var changed []string
+	for k, vs := range *dst {
+		if !equalValues(before[k], vs) {
+			changed = append(changed, k)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
 
-	for _, f := range embedded {
-		if err := reflectValue(header, f); err != nil {
-			return err
+// equalValues reports whether a and b hold the same values.
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
 		}
 	}
+	return true
+}
 
+// addHeader adds the fields of h, encoded from field f of the struct val,
+// canonicalizing their names if canonical is set.
+func (e *encodeState) addHeader(val reflect.Value, path string, f *field, h http.Header, canonical bool) error {
+	for k, vs := range h {
+		if canonical {
+			k = e.fieldKey(f, k)
+		}
+		for _, v := range vs {
+			if err := e.add(val, path, f, k, v); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
+// joinValues returns the elements of the slice or array sv joined by the
+// list separator of f, ", " for the "comma" option, to be added as the
+// Header field key.
+func (e *encodeState) joinValues(val reflect.Value, path string, f *field, key string, sv reflect.Value) (string, error) {
+	elems := make([]string, sv.Len())
+	for i := range elems {
+		s, err := e.valueString(sv.Index(i), f.opts)
+		if err != nil {
+			return "", err
+		}
+		if !validListElement(s, f.sep) {
+			return "", &InvalidListElementError{
+				Struct:  e.typ.Name(),
+				Field:   fieldPath(val.Type(), path, f.index),
+				Header:  key,
+				Element: s,
+			}
+		}
+		elems[i] = s
+	}
+	return joinList(elems, f.sep), nil
+}
+
+// isDefault reports whether sv encodes to the values of the "default" option
+// of f.
+func (e *HeaderEncoder) isDefault(sv reflect.Value, f *field) (bool, error) {
+	var vals []string
+	switch f.kind {
+	case kindValue:
+		s, err := e.valueString(sv, f.opts)
+		if err != nil {
+			return false, err
+		}
+		vals = []string{s}
+	case kindSlice:
+		for i := 0; i < sv.Len(); i++ {
+			s, err := e.valueString(sv.Index(i), f.opts)
+			if err != nil {
+				return false, err
+			}
+			vals = append(vals, s)
+		}
+	default:
+		return false, nil
+	}
+
+	if len(vals) != len(f.defaults) {
+		return false, nil
+	}
+	for i, s := range vals {
+		if s != f.defaults[i] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
 // valueString returns the string representation of a value.
-func valueString(v reflect.Value, opts tagOptions) string {
-	for v.Kind() == reflect.Ptr {
-		if v.IsNil() {
-			return ""
+func (e *HeaderEncoder) valueString(v reflect.Value, opts tagOptions) (string, error) {
+	for {
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return "", nil
+		}
+		if fn := e.encodeFunc(v.Type()); fn != nil {
+			return fn(v)
+		}
+		if v.Kind() != reflect.Ptr {
+			break
 		}
 		v = v.Elem()
 	}
 
 	if v.Kind() == reflect.Bool && opts.Contains("int") {
 		if v.Bool() {
-			return "1"
+			return "1", nil
 		}
-		return "0"
+		return "0", nil
 	}
 
 	if v.Type() == timeType {
-		t := v.Interface().(time.Time)
-		if opts.Contains("unix") {
-			return strconv.FormatInt(t.Unix(), 10)
+		return e.formatTime(v.Interface().(time.Time), opts), nil
+	}
+
+	if v.Type() == durationType {
+		return formatDuration(time.Duration(v.Int()), opts), nil
+	}
+
+	if m, ok := textMarshaler(v); ok {
+		b, err := m.MarshalText()
+		return string(b), err
+	}
+
+	if e.strict {
+		switch v.Kind() {
+		case reflect.Interface:
+			if v.IsNil() {
+				return "", nil
+			}
+			return e.valueString(v.Elem(), opts)
+		case reflect.Map, reflect.Struct, reflect.Chan, reflect.Func, reflect.UnsafePointer:
+			return "", &UnsupportedTypeError{v.Type()}
 		}
-		return t.Format(http.TimeFormat)
 	}
 
-	return fmt.Sprint(v.Interface())
+	return fmt.Sprint(v.Interface()), nil
+}
+
+// textMarshaler returns v as an encoding.TextMarshaler if either v or a
+// pointer to v implements it.
+func textMarshaler(v reflect.Value) (encoding.TextMarshaler, bool) {
+	if v.Type().Implements(textMarshalerType) {
+		return v.Interface().(encoding.TextMarshaler), true
+	}
+	if !reflect.PtrTo(v.Type()).Implements(textMarshalerType) {
+		return nil, false
+	}
+	if !v.CanAddr() {
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+		v = p.Elem()
+	}
+	return v.Addr().Interface().(encoding.TextMarshaler), true
+}
+
+// formatTime returns the string representation of t according to the time
+// options in opts.
+func (e *HeaderEncoder) formatTime(t time.Time, opts tagOptions) string {
+	switch {
+	case opts.Contains("unix"):
+		return strconv.FormatInt(t.Unix(), 10)
+	case opts.Contains("unixmilli"):
+		return strconv.FormatInt(t.Unix()*1e3+int64(t.Nanosecond())/1e6, 10)
+	case opts.Contains("unixnano"):
+		return strconv.FormatInt(t.UnixNano(), 10)
+	case opts.Contains("rfc3339"):
+		return t.Format(time.RFC3339Nano)
+	}
+	layout, ok := opts.Value("layout")
+	if !ok {
+		layout = e.timeLayout
+	}
+	if layout == http.TimeFormat {
+		t = t.UTC()
+	}
+	return t.Format(layout)
+}
+
+// formatDuration returns the string representation of d according to the
+// duration options in opts.
+func formatDuration(d time.Duration, opts tagOptions) string {
+	switch {
+	case opts.Contains("seconds"):
+		return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+	case opts.Contains("millis"):
+		return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64)
+	}
+	return d.String()
 }
 
 // isEmptyValue checks if a value should be considered empty for the purposes
@@ -272,23 +768,12 @@ func isEmptyValue(v reflect.Value) bool {
 
 // tagOptions is the string following a comma in a struct field's "header" tag, or
 // the empty string. It does not include the leading comma.
-type tagOptions []string
+type tagOptions = fieldtag.Options
 
 // parseTag splits a struct field's header tag into its name and comma-separated
-// options.
+// options, see fieldtag.Parse.
 func parseTag(tag string) (string, tagOptions) {
-	s := strings.Split(tag, ",")
-	return s[0], s[1:]
-}
-
-// Contains checks whether the tagOptions contains the specified option.
-func (o tagOptions) Contains(option string) bool {
-	for _, s := range o {
-		if s == option {
-			return true
-		}
-	}
-	return false
+	return fieldtag.Parse(tag)
 }
 
 // Encode is an alias of Header function
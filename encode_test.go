@@ -2,6 +2,7 @@ package httpheader
 
 import (
 	"fmt"
+	"net"
 	"net/http"
 	"reflect"
 	"testing"
@@ -246,6 +247,37 @@ func TestHeader_Marshaler(t *testing.T) {
 	}
 }
 
+// overrideEncoder sets the Header field key, reading the value of the
+// Header field X-A encoded before it.
+type overrideEncoder struct{}
+
+func (overrideEncoder) EncodeHeader(key string, v *http.Header) error {
+	v.Set(key, "override:"+v.Get("X-A"))
+	v.Set("X-A", "override")
+	v.Del("X-B")
+	return nil
+}
+
+func TestHeader_MarshalerSet(t *testing.T) {
+	s := struct {
+		A string          `header:"X-A"`
+		B string          `header:"X-B"`
+		O overrideEncoder `header:"X-O"`
+	}{A: "a", B: "b"}
+	v, err := Header(s)
+	if err != nil {
+		t.Errorf("Header(%+v) returned error: %v", s, err)
+	}
+
+	want := http.Header{
+		"X-A": []string{"override"},
+		"X-O": []string{"override:a"},
+	}
+	if !reflect.DeepEqual(want, v) {
+		t.Errorf("Header(%+v) returned %v, want %v", s, v, want)
+	}
+}
+
 func TestHeader_MarshalerWithNilPointer(t *testing.T) {
 	s := struct {
 		Args *EncodedArgs `header:"Arg"`
@@ -280,3 +312,413 @@ func TestTagParsing(t *testing.T) {
 		}
 	}
 }
+
+type benchOptions struct {
+	ContentType   string    `header:"Content-Type"`
+	ContentLength int64     `header:"Content-Length"`
+	ACL           string    `header:"X-Cos-Acl,omitempty"`
+	Expires       time.Time `header:"Expires,omitempty"`
+	Tags          []string  `header:"X-Cos-Tag"`
+	Private       bool      `header:"X-Private,int"`
+	B
+}
+
+func BenchmarkHeader(b *testing.B) {
+	opt := benchOptions{
+		ContentType:   "application/json",
+		ContentLength: 1024,
+		Expires:       time.Date(2000, 1, 1, 12, 34, 56, 0, time.UTC),
+		Tags:          []string{"a", "b"},
+		Private:       true,
+		B:             B{C: "c"},
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := Header(opt); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+type textLevel int
+
+var textLevels = []string{"low", "high"}
+
+func (l textLevel) MarshalText() ([]byte, error) {
+	if int(l) >= len(textLevels) {
+		return nil, fmt.Errorf("invalid level %d", l)
+	}
+	return []byte(textLevels[l]), nil
+}
+
+func (l *textLevel) UnmarshalText(b []byte) error {
+	for i, s := range textLevels {
+		if s == string(b) {
+			*l = textLevel(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid level %q", b)
+}
+
+func TestHeader_TextMarshaler(t *testing.T) {
+	high := textLevel(1)
+	s := struct {
+		A textLevel
+		B *textLevel
+		C []textLevel
+		D net.IP
+		E []net.IP
+		F *textLevel
+	}{
+		A: 1,
+		B: &high,
+		C: []textLevel{0, 1},
+		D: net.IPv4(192, 168, 0, 1),
+		E: []net.IP{net.IPv4(10, 0, 0, 1), net.ParseIP("::1")},
+	}
+	v, err := Header(s)
+	if err != nil {
+		t.Errorf("Header(%+v) returned error: %v", s, err)
+	}
+
+	want := http.Header{
+		"A": []string{"high"},
+		"B": []string{"high"},
+		"C": []string{"low", "high"},
+		"D": []string{"192.168.0.1"},
+		"E": []string{"10.0.0.1", "::1"},
+		"F": []string{""},
+	}
+	if !reflect.DeepEqual(want, v) {
+		t.Errorf("Header(%+v) returned %v, want %v", s, v, want)
+	}
+
+	_, err = Header(struct{ A []textLevel }{[]textLevel{2}})
+	if err == nil {
+		t.Error("expected Header() to return the MarshalText error")
+	}
+}
+
+func TestHeader_prefix(t *testing.T) {
+	type metaMap map[string]string
+	s := struct {
+		Meta  map[string]string   `header:"X-Cos-Meta-,prefix"`
+		Multi map[string][]string `header:"x-amz-meta-,prefix"`
+		Named metaMap             `header:"X-Named-,prefix"`
+		Empty map[string]string   `header:"X-Empty-,prefix,omitempty"`
+		Nil   map[string]string   `header:"X-Nil-,prefix"`
+	}{
+		Meta:  map[string]string{"foo": "bar", "Hello-World": "1"},
+		Multi: map[string][]string{"a": {"1", "2"}},
+		Named: metaMap{"x": "y"},
+	}
+	v, err := Header(s)
+	if err != nil {
+		t.Errorf("Header(%+v) returned error: %v", s, err)
+	}
+
+	want := http.Header{
+		"X-Cos-Meta-Foo":         []string{"bar"},
+		"X-Cos-Meta-Hello-World": []string{"1"},
+		"X-Amz-Meta-A":           []string{"1", "2"},
+		"X-Named-X":              []string{"y"},
+	}
+	if !reflect.DeepEqual(want, v) {
+		t.Errorf("Header(%+v) returned %v, want %v", s, v, want)
+	}
+}
+
+func TestHeader_omitDefault(t *testing.T) {
+	type defaults struct {
+		Timeout  int      `header:"X-Timeout,default=30"`
+		Debug    bool     `header:"X-Debug,int,default=1"`
+		Encoding []string `header:"X-Encoding,default=gzip;br"`
+		Name     string
+	}
+	tests := []struct {
+		in   defaults
+		want http.Header
+	}{
+		{
+			defaults{Timeout: 30, Debug: true, Encoding: []string{"gzip", "br"}, Name: "a"},
+			http.Header{"Name": []string{"a"}},
+		},
+		{
+			defaults{Timeout: 5, Encoding: []string{"gzip"}},
+			http.Header{
+				"X-Timeout":  []string{"5"},
+				"X-Debug":    []string{"0"},
+				"X-Encoding": []string{"gzip"},
+				"Name":       []string{""},
+			},
+		},
+	}
+
+	e := NewEncoder(WithOmitDefault())
+	for i, tt := range tests {
+		v, err := e.Encode(tt.in)
+		if err != nil {
+			t.Errorf("%d. Encode(%+v) returned error: %v", i, tt.in, err)
+		}
+		if !reflect.DeepEqual(tt.want, v) {
+			t.Errorf("%d. Encode(%+v) returned %v, want %v", i, tt.in, v, tt.want)
+		}
+	}
+
+	// defaults are encoded unless WithOmitDefault is used
+	v, err := Header(tests[0].in)
+	if err != nil {
+		t.Errorf("Header(%+v) returned error: %v", tests[0].in, err)
+	}
+	if len(v) != 4 {
+		t.Errorf("Header(%+v) returned %v, want 4 fields", tests[0].in, v)
+	}
+}
+
+func TestHeader_list(t *testing.T) {
+	s := struct {
+		Encoding []string     `header:"Accept-Encoding,comma"`
+		Vary     [2]string    `header:"Vary,comma"`
+		Levels   []textLevel  `header:"X-Levels,sep=;"`
+		Flags    []bool       `header:"X-Flags,int,sep=|"`
+		Empty    []string     `header:"X-Empty,comma"`
+		Allow    []string     `header:"Allow,comma"`
+		Ptr      *[]string    `header:"X-Ptr,comma"`
+		Single   []int        `header:"X-Single,comma"`
+		Nil      []*textLevel `header:"X-Nil,comma"`
+	}{
+		Encoding: []string{"gzip", "br"},
+		Vary:     [2]string{"Accept", "Origin"},
+		Levels:   []textLevel{0, 1},
+		Flags:    []bool{true, false},
+		Allow:    []string{"GET"},
+		Single:   []int{1},
+		Nil:      []*textLevel{nil, nil},
+	}
+	v, err := Header(s)
+	if err != nil {
+		t.Errorf("Header(%+v) returned error: %v", s, err)
+	}
+
+	want := http.Header{
+		"Accept-Encoding": []string{"gzip, br"},
+		"Vary":            []string{"Accept, Origin"},
+		"X-Levels":        []string{"low;high"},
+		"X-Flags":         []string{"1|0"},
+		"Allow":           []string{"GET"},
+		"X-Ptr":           []string{""},
+		"X-Single":        []string{"1"},
+		"X-Nil":           []string{", "},
+	}
+	if !reflect.DeepEqual(want, v) {
+		t.Errorf("Header(%+v) returned %v, want %v", s, v, want)
+	}
+}
+
+func TestHeader_listElements(t *testing.T) {
+	type list struct {
+		Comma []string `header:"X-Comma,comma"`
+		Sep   []string `header:"X-Sep,sep=;"`
+	}
+	in := list{
+		Comma: []string{`"xyzzy"`, `"r2d2,xxxx"`, `W/"c3\"p,o"`, "a b"},
+		Sep:   []string{"a,b", `"c;d"`},
+	}
+	h, err := Header(in)
+	if err != nil {
+		t.Fatalf("Header(%+v) returned error: %v", in, err)
+	}
+	var out list
+	if err := Decode(h, &out); err != nil {
+		t.Fatalf("Decode(%v) returned error: %v", h, err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("Decode(%v) = %+v, want %+v", h, out, in)
+	}
+
+	tests := []struct {
+		in      list
+		element string
+	}{
+		{list{Comma: []string{"a, b", `c"d`}}, "a, b"},
+		{list{Comma: []string{"a", `c"d`}}, `c"d`},
+		{list{Comma: []string{" a"}}, " a"},
+		{list{Sep: []string{"a", "b\t"}}, "b\t"},
+		{list{Sep: []string{`"a;b`}}, `"a;b`},
+	}
+	for _, tt := range tests {
+		_, err := Header(tt.in)
+		if e, ok := err.(*InvalidListElementError); !ok || e.Element != tt.element {
+			t.Errorf("Header(%+v) returned error %v, want *InvalidListElementError for %q", tt.in, err, tt.element)
+		}
+	}
+}
+
+func TestHeader_map(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want http.Header
+	}{
+		{map[string]string{"x-name": "foo", "X-Empty": ""}, http.Header{"X-Name": {"foo"}, "X-Empty": {""}}},
+		{map[string][]string{"x-list": {"a", "b"}}, http.Header{"X-List": {"a", "b"}}},
+		{http.Header{"accept": {"text/plain"}}, http.Header{"Accept": {"text/plain"}}},
+		{&map[string]interface{}{"X-Int": 1, "X-Level": textLevel(1)}, http.Header{"X-Int": {"1"}, "X-Level": {"high"}}},
+		{map[string]string(nil), http.Header{}},
+		{map[string]interface{}{"X-Nil": nil, "X-Ptr": (*int)(nil)}, http.Header{"X-Nil": {""}, "X-Ptr": {""}}},
+	}
+	for _, tt := range tests {
+		got, err := Header(tt.in)
+		if err != nil {
+			t.Errorf("Header(%v) returned error: %v", tt.in, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Header(%v) returned %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	_, err := Header(map[string]string{"X-Name": "a\nb"})
+	if e, ok := err.(*InvalidHeaderError); !ok || e.Field != "X-Name" {
+		t.Errorf("Header returned error %#v, want *InvalidHeaderError for X-Name", err)
+	}
+	if _, err := Header(map[int]string{1: "a"}); err == nil {
+		t.Error("Header(map[int]string) returned no error")
+	}
+}
+
+func TestHeader_raw(t *testing.T) {
+	s := struct {
+		A    string            `header:"x-a,raw"`
+		B    string            `header:"x-b"`
+		Meta map[string]string `header:"x-cos-meta-,prefix,raw"`
+		H    http.Header       `header:",raw"`
+	}{
+		A:    "a",
+		B:    "b",
+		Meta: map[string]string{"MyKey": "v"},
+		H:    http.Header{"x-h": {"h"}},
+	}
+	v, err := Header(s)
+	if err != nil {
+		t.Errorf("Header(%+v) returned error: %v", s, err)
+	}
+	want := http.Header{"x-a": {"a"}, "X-B": {"b"}, "x-cos-meta-MyKey": {"v"}, "x-h": {"h"}}
+	if !reflect.DeepEqual(want, v) {
+		t.Errorf("Header(%+v) returned %v, want %v", s, v, want)
+	}
+}
+
+func TestHeader_timeOptions(t *testing.T) {
+	tm := time.Date(2000, 1, 1, 12, 34, 56, 789000000, time.FixedZone("CST", 8*3600))
+	s := struct {
+		Default   time.Time
+		Unix      time.Time   `header:",unix"`
+		UnixMilli time.Time   `header:",unixmilli"`
+		UnixNano  *time.Time  `header:",unixnano"`
+		RFC3339   time.Time   `header:",rfc3339"`
+		Layout    time.Time   `header:",omitempty,layout=Mon, 02 Jan 2006 15:04"`
+		List      []time.Time `header:",rfc3339,comma"`
+	}{tm, tm, tm, &tm, tm, tm, []time.Time{tm, tm.UTC()}}
+	v, err := Header(s)
+	if err != nil {
+		t.Errorf("Header(%+v) returned error: %v", s, err)
+	}
+
+	want := http.Header{
+		"Default":   []string{"Sat, 01 Jan 2000 04:34:56 GMT"},
+		"Unix":      []string{"946701296"},
+		"Unixmilli": []string{"946701296789"},
+		"Unixnano":  []string{"946701296789000000"},
+		"Rfc3339":   []string{"2000-01-01T12:34:56.789+08:00"},
+		"Layout":    []string{"Sat, 01 Jan 2000 12:34"},
+		"List":      []string{"2000-01-01T12:34:56.789+08:00, 2000-01-01T04:34:56.789Z"},
+	}
+	if !reflect.DeepEqual(want, v) {
+		t.Errorf("Header(%+v) returned %v, want %v", s, v, want)
+	}
+}
+
+func TestHeader_durationOptions(t *testing.T) {
+	s := struct {
+		Default  time.Duration
+		GoDur    time.Duration   `header:",godur"`
+		Seconds  time.Duration   `header:",seconds"`
+		Fraction time.Duration   `header:",seconds"`
+		Millis   *time.Duration  `header:",millis"`
+		List     []time.Duration `header:",seconds,comma"`
+	}{
+		90 * time.Second,
+		1500 * time.Millisecond,
+		2 * time.Minute,
+		1500 * time.Millisecond,
+		func() *time.Duration { d := 2500 * time.Microsecond; return &d }(),
+		[]time.Duration{time.Second, time.Minute},
+	}
+	v, err := Header(s)
+	if err != nil {
+		t.Errorf("Header(%+v) returned error: %v", s, err)
+	}
+
+	want := http.Header{
+		"Default":  []string{"1m30s"},
+		"Godur":    []string{"1.5s"},
+		"Seconds":  []string{"120"},
+		"Fraction": []string{"1.5"},
+		"Millis":   []string{"2.5"},
+		"List":     []string{"1, 60"},
+	}
+	if !reflect.DeepEqual(want, v) {
+		t.Errorf("Header(%+v) returned %v, want %v", s, v, want)
+	}
+}
+
+func TestTagParsing_layout(t *testing.T) {
+	name, opts := parseTag("X-Date,omitempty,layout=Mon, 02 Jan 2006")
+	if name != "X-Date" {
+		t.Fatalf("name = %+v, want X-Date", name)
+	}
+	if want := (tagOptions{"omitempty", "layout=Mon, 02 Jan 2006"}); !reflect.DeepEqual(want, opts) {
+		t.Errorf("opts = %#v, want %#v", opts, want)
+	}
+	if layout, ok := opts.Value("layout"); !ok || layout != "Mon, 02 Jan 2006" {
+		t.Errorf("Value(layout) = %q, %v", layout, ok)
+	}
+}
+
+func TestEncodeInto(t *testing.T) {
+	type layer struct {
+		Agent   string   `header:"User-Agent,omitempty"`
+		Accept  []string `header:"Accept,omitempty"`
+		Timeout int      `header:"X-Timeout,omitempty"`
+	}
+	h := http.Header{"X-Request-Id": {"1"}}
+	if err := EncodeInto(h, layer{Accept: []string{"text/plain"}, Timeout: 5}, MergeSet); err != nil {
+		t.Errorf("EncodeInto returned error: %v", err)
+	}
+	if err := EncodeInto(h, &layer{Agent: "default", Accept: []string{"*/*"}, Timeout: 30}, MergeKeepExisting); err != nil {
+		t.Errorf("EncodeInto returned error: %v", err)
+	}
+	if err := EncodeInto(h, layer{Accept: []string{"text/html", "text/plain"}}, MergeSet); err != nil {
+		t.Errorf("EncodeInto returned error: %v", err)
+	}
+	if err := EncodeInto(h, layer{Timeout: 10}, MergeAdd); err != nil {
+		t.Errorf("EncodeInto returned error: %v", err)
+	}
+
+	want := http.Header{
+		"X-Request-Id": {"1"},
+		"User-Agent":   {"default"},
+		"Accept":       {"text/html", "text/plain"},
+		"X-Timeout":    {"5", "10"},
+	}
+	if !reflect.DeepEqual(h, want) {
+		t.Errorf("EncodeInto got %v, want %v", h, want)
+	}
+
+	if err := EncodeInto(nil, layer{}, MergeAdd); err == nil {
+		t.Error("expected EncodeInto to return an error for a nil header")
+	}
+	if err := EncodeInto(h, nil, MergeAdd); err != nil {
+		t.Errorf("EncodeInto(nil) returned error: %v", err)
+	}
+}
@@ -176,3 +176,25 @@ func ExampleDecoder() {
 	// <nil>
 	// foobar
 }
+
+func ExampleNewEncoder() {
+	type Options struct {
+		RequestTime time.Time `hdr:"X-Request-Time"`
+		Debug       bool      `hdr:"X-Debug,int"`
+	}
+
+	enc := httpheader.NewEncoder(
+		httpheader.WithTagName("hdr"),
+		httpheader.WithTimeLayout(time.RFC3339),
+	)
+	h, err := enc.Encode(Options{
+		RequestTime: time.Date(2000, 1, 1, 12, 34, 56, 0, time.UTC),
+		Debug:       true,
+	})
+	fmt.Println(err)
+	printHeader(h)
+	// Output:
+	// <nil>
+	// X-Debug: []string{"1"}
+	// X-Request-Time: []string{"2000-01-01T12:34:56Z"}
+}
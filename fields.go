@@ -0,0 +1,301 @@
+package httpheader
+
+import (
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"reflect"
+	"strings"
+	"sync/atomic"
+
+	"github.com/mozillazg/go-httpheader/internal/fieldtag"
+)
+
+// fieldKind selects how a struct field is encoded or decoded. The kinds and
+// the tag parsing are shared with the httpheader-gen command.
+type fieldKind = fieldtag.Kind
+
+const (
+	kindValue       = fieldtag.KindValue
+	kindSlice       = fieldtag.KindSlice
+	kindMulti       = fieldtag.KindMulti
+	kindPtr         = fieldtag.KindPtr
+	kindStruct      = fieldtag.KindStruct
+	kindHeader      = fieldtag.KindHeader
+	kindPrefix      = fieldtag.KindPrefix
+	kindEncoder     = fieldtag.KindEncoder
+	kindDecoder     = fieldtag.KindDecoder
+	kindDecoderAddr = fieldtag.KindDecoderAddr
+)
+
+// field is a precompiled struct field, or a field of a nested or embedded
+// struct, that is encoded to or decoded from a Header field.
+type field struct {
+	name      string // Header field name as given by the tag or struct field
+	key       string // canonical format of name
+	index     []int  // index sequence for reflect.Value.FieldByIndex
+	typ       reflect.Type
+	opts      tagOptions
+	omitEmpty bool
+	required  bool
+	defaults  []string // values of the "default" option, nil if unset
+	sep       string   // list separator of the "comma" or "sep" option
+	trailer   bool
+	raw       bool   // Header field names are used verbatim
+	dup       string // value of the "dup" option
+	kind      fieldKind
+}
+
+// kindFunc chooses how a struct field is handled. It reports whether the
+// field is a struct whose fields should be handled in place instead.
+type kindFunc func(sf reflect.StructField, opts tagOptions) (fieldKind, bool)
+
+// cachedFields holds the fields of a type, computed when the converter
+// registrations were at generation gen.
+type cachedFields struct {
+	gen    uint64
+	fields []field
+	err    error
+}
+
+// cachedTypeFields is like typeFields but uses a cache to avoid repeated work.
+func (c *config) cachedTypeFields(t reflect.Type) ([]field, error) {
+	gen := atomic.LoadUint64(&converterGen)
+	if f, ok := c.fieldCache.Load(t); ok && f.(*cachedFields).gen == gen {
+		return f.(*cachedFields).fields, f.(*cachedFields).err
+	}
+	f := &cachedFields{gen: gen}
+	f.fields, f.err = typeFields(c, t, nil)
+	c.fieldCache.Store(t, f)
+	return f.fields, f.err
+}
+
+// typeFields returns the fields of struct type t in the order they are
+// encoded or decoded, using c.kindOf to choose how each of them is handled.
+// Nested structs are flattened in place, embedded structs are appended
+// after the fields of t (using the rules defined in the Header function
+// documentation). It returns an error if a tag option has an invalid value.
+func typeFields(c *config, t reflect.Type, index []int) ([]field, error) {
+	var fields, embedded []field
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous { // unexported
+			continue
+		}
+
+		tag := sf.Tag.Get(c.tagName)
+		if tag == "-" {
+			continue
+		}
+		idx := make([]int, len(index)+1)
+		copy(idx, index)
+		idx[len(index)] = i
+
+		name, opts := parseTag(tag)
+		if name == "" {
+			if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+				// save embedded struct for later processing
+				fs, err := typeFields(c, sf.Type, idx)
+				if err != nil {
+					return nil, err
+				}
+				embedded = append(embedded, fs...)
+				continue
+			}
+			if sf.PkgPath != "" && indirectType(sf.Type).Kind() != reflect.Struct {
+				// unexported non-struct embedded fields cannot be accessed
+				continue
+			}
+			name = sf.Name
+		}
+
+		kind, flatten := c.kindOf(sf, opts)
+		if flatten {
+			fs, err := typeFields(c, sf.Type, idx)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, fs...)
+			continue
+		}
+		dup, err := fieldtag.Dup(opts)
+		if err != nil {
+			field := sf.Name
+			if t.Name() != "" {
+				field = t.Name() + "." + field
+			}
+			return nil, fmt.Errorf("httpheader: field %s: %v", field, err)
+		}
+		sep := fieldtag.ListSeparator(opts)
+		fields = append(fields, field{
+			name:      name,
+			key:       textproto.CanonicalMIMEHeaderKey(name),
+			index:     idx,
+			typ:       sf.Type,
+			opts:      opts,
+			omitEmpty: opts.Contains("omitempty") && kind != kindPrefix && kind != kindHeader,
+			required:  opts.Contains("required"),
+			trailer:   opts.Contains("trailer"),
+			raw:       opts.Contains("raw"),
+			defaults:  c.defaultValues(sf.Type, kind, opts, sep),
+			sep:       sep,
+			dup:       dup,
+			kind:      kind,
+		})
+	}
+
+	return append(fields, embedded...), nil
+}
+
+// defaultValues returns the values of the "default" option of a field of
+// type t, splitting them with the list separator sep, or the default
+// separator, if t holds multiple values.
+func (c *config) defaultValues(t reflect.Type, kind fieldKind, opts tagOptions, sep string) []string {
+	list := false
+	switch kind {
+	case kindSlice, kindMulti, kindPtr:
+		k := indirectType(t).Kind()
+		list = k == reflect.Slice || k == reflect.Array
+	}
+	return fieldtag.Defaults(opts, list, sep, c.defaultSep)
+}
+
+// joinList joins the list elements elems by sep, ", " for ",".
+func joinList(elems []string, sep string) string {
+	if sep == "," {
+		sep = ", "
+	}
+	return strings.Join(elems, sep)
+}
+
+// validListElement reports whether fieldtag.SplitList decodes s back as is from a
+// list joined by sep: s has no leading or trailing whitespace, holds sep only
+// in quoted strings, and terminates its quoted strings.
+func validListElement(s, sep string) bool {
+	if s != strings.Trim(s, " \t") {
+		return false
+	}
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quoted && s[i] == '\\':
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case !quoted && strings.HasPrefix(s[i:], sep):
+			return false
+		}
+	}
+	return !quoted
+}
+
+// encodeKind is the kindFunc of e.
+func (e *HeaderEncoder) encodeKind(sf reflect.StructField, opts tagOptions) (fieldKind, bool) {
+	t := sf.Type
+	if t.Implements(encoderType) && !hasGeneratedMethods(t) {
+		return kindEncoder, false
+	}
+	if opts.Contains("prefix") && isPrefixMap(t) {
+		return kindPrefix, false
+	}
+	if e.hasEncodeFunc(t) {
+		return kindValue, false
+	}
+	base := indirectType(t)
+	if base == timeType || implementsText(base, textMarshalerType) {
+		return kindValue, false
+	}
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		return kindSlice, false
+	}
+
+	switch {
+	case base == headerType:
+		return kindHeader, false
+	case base.Kind() == reflect.Struct:
+		return kindStruct, base == t
+	}
+	return kindValue, false
+}
+
+// decodeKind is the kindFunc of d.
+func (d *HeaderDecoder) decodeKind(sf reflect.StructField, opts tagOptions) (fieldKind, bool) {
+	t := sf.Type
+	if sf.PkgPath == "" && !hasGeneratedMethods(t) {
+		if t.Kind() != reflect.Ptr && t.Name() != "" && reflect.PtrTo(t).Implements(decoderType) {
+			return kindDecoderAddr, false
+		}
+		if t.Implements(decoderType) {
+			return kindDecoder, false
+		}
+	}
+	if opts.Contains("prefix") && isPrefixMap(t) {
+		return kindPrefix, false
+	}
+	if d.decodeFunc(t) != nil {
+		return kindValue, false
+	}
+	if t != timeType && t.Kind() != reflect.Ptr && implementsText(t, textUnmarshalerType) {
+		return kindValue, false
+	}
+	if t == headerType {
+		return kindHeader, false
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return kindPtr, false
+	case reflect.Struct:
+		if t == timeType {
+			return kindMulti, false
+		}
+		// a struct with the omitempty option is only decoded when its
+		// Header field is present, so it can't be flattened.
+		return kindStruct, !opts.Contains("omitempty")
+	case reflect.Slice, reflect.Array, reflect.Interface:
+		return kindMulti, false
+	}
+	return kindValue, false
+}
+
+// isPrefixMap reports whether t can hold Header fields sharing a name
+// prefix, that is whether it is a map[string]string or a map[string][]string.
+func isPrefixMap(t reflect.Type) bool {
+	if t.Kind() != reflect.Map || t.Key().Kind() != reflect.String {
+		return false
+	}
+	elem := t.Elem()
+	if elem.Kind() == reflect.Slice {
+		elem = elem.Elem()
+	}
+	return elem.Kind() == reflect.String
+}
+
+// implementsText reports whether t, or a pointer to t, implements the
+// encoding.TextMarshaler or encoding.TextUnmarshaler interface iface.
+func implementsText(t, iface reflect.Type) bool {
+	return t.Implements(iface) || reflect.PtrTo(t).Implements(iface)
+}
+
+// indirectType returns the type that t points to, following any number of
+// pointers.
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// indirect follows the pointers in v, stopping at the first nil pointer.
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v
+}
+
+// addValue appends value to the values of the canonical key in header.
+func addValue(header http.Header, key, value string) {
+	header[key] = append(header[key], value)
+}
@@ -0,0 +1,58 @@
+package httpheader
+
+import (
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestCachedTypeFields(t *testing.T) {
+	typ := reflect.TypeOf(D{})
+	var wg sync.WaitGroup
+	e := NewEncoder()
+	got := make([][]field, 8)
+	for i := range got {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			got[i], _ = e.cachedTypeFields(typ)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, f := range got {
+		if &f[0] != &got[0][0] {
+			t.Errorf("%d. cachedTypeFields returned %p, want %p", i, f, got[0])
+		}
+	}
+
+	var names []string
+	var indexes [][]int
+	for _, f := range got[0] {
+		names = append(names, f.name)
+		indexes = append(indexes, f.index)
+	}
+	if want := []string{"C", "C"}; !reflect.DeepEqual(want, names) {
+		t.Errorf("encode fields = %v, want %v", names, want)
+	}
+	if want := [][]int{{1}, {0, 0}}; !reflect.DeepEqual(want, indexes) {
+		t.Errorf("encode field indexes = %v, want %v", indexes, want)
+	}
+}
+
+func TestCachedTypeFields_recursiveType(t *testing.T) {
+	type node struct {
+		Name string
+		Next *node
+	}
+	v := node{Name: "a", Next: &node{Name: "b"}}
+	h, err := Header(v)
+	if err != nil {
+		t.Fatalf("Header returned error: %v", err)
+	}
+	want := http.Header{"Name": []string{"a", "b"}, "Next": []string{""}}
+	if !reflect.DeepEqual(want, h) {
+		t.Errorf("Header(%+v) returned %v, want %v", v, h, want)
+	}
+}
@@ -0,0 +1,257 @@
+package httpheader
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/mozillazg/go-httpheader/internal/fieldtag"
+)
+
+// generatedStruct is implemented by the structs whose methods are generated
+// by httpheader-gen. HTTPHeaderGenerated returns a nil pointer to the struct
+// type, so that the methods promoted from an embedded generated struct are
+// told apart from those of the outer struct.
+type generatedStruct interface {
+	HTTPHeaderGenerated() interface{}
+}
+
+var generatedType = reflect.TypeOf(new(generatedStruct)).Elem()
+
+// hasGeneratedMethods reports whether t is a struct, or a pointer to a
+// struct, whose methods are generated by httpheader-gen or promoted from an
+// embedded struct whose methods are. Reflection handles the fields of such
+// types as plain structs rather than Encoders or Decoders, so that the
+// options of a HeaderEncoder or HeaderDecoder apply to their fields.
+func hasGeneratedMethods(t reflect.Type) bool {
+	t = indirectType(t)
+	return t.Kind() == reflect.Struct && t.Implements(generatedType)
+}
+
+// isGenerated reports whether v is a struct, or a non-nil pointer to a
+// struct, whose methods are generated by httpheader-gen. Other structs
+// implementing Encoder or Decoder are encoded and decoded by reflection,
+// since their methods may expect a non-empty key or call Header and Decode
+// themselves.
+func isGenerated(v interface{}) bool {
+	g, ok := v.(generatedStruct)
+	if !ok {
+		return false
+	}
+	val := reflect.ValueOf(v)
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return false
+		}
+		val = val.Elem()
+	}
+	return val.Kind() == reflect.Struct &&
+		reflect.TypeOf(g.HTTPHeaderGenerated()) == reflect.PtrTo(val.Type())
+}
+
+// structEncoder returns v as an Encoder if v is a struct, or a non-nil
+// pointer to a struct, whose methods are generated by httpheader-gen.
+func structEncoder(v interface{}) (Encoder, bool) {
+	m, ok := v.(Encoder)
+	if !ok || !isGenerated(v) {
+		return nil, false
+	}
+	return m, true
+}
+
+// structDecoder returns v as a Decoder if v is a non-nil pointer to a struct
+// whose methods are generated by httpheader-gen.
+func structDecoder(v interface{}) (Decoder, bool) {
+	m, ok := v.(Decoder)
+	if !ok || reflect.ValueOf(v).Kind() != reflect.Ptr || !isGenerated(v) {
+		return nil, false
+	}
+	return m, true
+}
+
+// GeneratedEncoder is used by the EncodeHeader methods generated by
+// httpheader-gen to encode Header fields with the same rules as Header, see
+// the cmd/httpheader-gen command. It is not meant to be used directly.
+type GeneratedEncoder struct {
+	Header http.Header
+	Struct string // name of the struct type being encoded
+}
+
+// Add appends value to the values of the Header field name, encoded from the
+// struct field at path. It returns an *InvalidHeaderError if name or value
+// is not valid.
+func (e *GeneratedEncoder) Add(path, name, value string) error {
+	if validHeaderName(name) && validHeaderValue(value) {
+		addValue(e.Header, name, value)
+		return nil
+	}
+	err := &InvalidHeaderError{Struct: e.Struct, Field: path, Header: name}
+	if validHeaderName(name) {
+		err.Value = value
+	}
+	return err
+}
+
+// AddList adds the list elements vals, encoded from the struct field at path,
+// joined by sep as the Header field name. It returns an
+// *InvalidListElementError if an element wouldn't decode back as is.
+func (e *GeneratedEncoder) AddList(path, name string, vals []string, sep string) error {
+	for _, s := range vals {
+		if !validListElement(s, sep) {
+			return &InvalidListElementError{Struct: e.Struct, Field: path, Header: name, Element: s}
+		}
+	}
+	return e.Add(path, name, joinList(vals, sep))
+}
+
+// Encode calls the EncodeHeader method of m, the struct field at path, with
+// key and the Header being encoded. It returns an *InvalidHeaderError if a
+// Header field it adds or changes is not valid.
+func (e *GeneratedEncoder) Encode(path, key string, m Encoder) error {
+	changed, err := encodeHeader(m, key, &e.Header)
+	if err != nil {
+		return err
+	}
+	for _, k := range changed {
+		vs := e.Header[k]
+		delete(e.Header, k)
+		for _, v := range vs {
+			if err := e.Add(path, k, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// AddHeader adds the fields of h, encoded from the struct field at path,
+// canonicalizing their names if canonical is set.
+func (e *GeneratedEncoder) AddHeader(path string, h http.Header, canonical bool) error {
+	for k, vs := range h {
+		if canonical {
+			k = http.CanonicalHeaderKey(k)
+		}
+		for _, v := range vs {
+			if err := e.Add(path, k, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// FormatTime returns the encoding of t according to the tag options of its
+// field, e.g. "unix".
+func (e *GeneratedEncoder) FormatTime(t time.Time, options string) string {
+	return defaultEncoder.formatTime(t, splitOptions(options))
+}
+
+// FormatDuration returns the encoding of d according to the tag options of
+// its field, e.g. "seconds".
+func (e *GeneratedEncoder) FormatDuration(d time.Duration, options string) string {
+	return formatDuration(d, splitOptions(options))
+}
+
+// GeneratedDecoder is used by the DecodeHeader methods generated by
+// httpheader-gen to decode Header fields with the same rules as Decode, see
+// the cmd/httpheader-gen command. It is not meant to be used directly.
+type GeneratedDecoder struct {
+	Header  http.Header
+	Struct  string // name of the struct type being decoded
+	missing *MissingHeaderError
+}
+
+// Present reports whether the Header field key is present, or any Header
+// field whose name starts with key if prefix is set.
+func (d *GeneratedDecoder) Present(key string, prefix bool) bool {
+	return present(d.Header, key, prefix, true)
+}
+
+// Missing records the Header field name of the required struct field at
+// path as missing, see Err.
+func (d *GeneratedDecoder) Missing(path, name string) {
+	if d.missing == nil {
+		d.missing = &MissingHeaderError{Struct: d.Struct}
+	}
+	d.missing.Headers = append(d.missing.Headers, name)
+	d.missing.Fields = append(d.missing.Fields, path)
+}
+
+// Err returns a *MissingHeaderError listing the Header fields recorded by
+// Missing, or nil if there is none.
+func (d *GeneratedDecoder) Err() error {
+	if d.missing != nil {
+		return d.missing
+	}
+	return nil
+}
+
+// Error returns a *DecodeError for the values vals of the Header field name
+// that could not be decoded into the struct field at path.
+func (d *GeneratedDecoder) Error(path, name string, vals []string, err error) error {
+	return &DecodeError{
+		Struct: d.Struct,
+		Field:  path,
+		Header: name,
+		Value:  strings.Join(vals, ", "),
+		Err:    err,
+	}
+}
+
+// SplitList splits each of vals into list elements separated by sep, see
+// the "comma" and "sep" options.
+func (d *GeneratedDecoder) SplitList(vals []string, sep string) []string {
+	return fieldtag.SplitList(vals, sep)
+}
+
+// Duplicates returns the values vals of the Header field name to decode
+// into the struct field at path according to the "dup" option in options, or
+// a *DuplicateHeaderError.
+func (d *GeneratedDecoder) Duplicates(path, name string, vals []string, options string) ([]string, error) {
+	opts := splitOptions(options)
+	dup, _ := opts.Value("dup")
+	v, ok := duplicateValues(duplicatePolicies[dup], opts, vals)
+	if !ok {
+		return nil, &DuplicateHeaderError{Struct: d.Struct, Field: path, Header: name, Values: vals}
+	}
+	return v, nil
+}
+
+// Prefixed calls fn with the Header fields whose names start with prefix,
+// ignoring case, passing the rest of their names.
+func (d *GeneratedDecoder) Prefixed(prefix string, fn func(key string, vals []string)) {
+	for k, vs := range d.Header {
+		if hasKeyPrefix(k, prefix, true) && len(vs) > 0 {
+			fn(k[len(prefix):], vs)
+		}
+	}
+}
+
+// Rest returns a copy of the Header fields that are neither named by known
+// nor start with one of prefixes, ignoring case, or nil if there is none.
+func (d *GeneratedDecoder) Rest(known, prefixes []string) http.Header {
+	m := make(map[string]bool, len(known))
+	for _, k := range known {
+		m[k] = true
+	}
+	return restHeader(d.Header, m, prefixes, defaultDecoder.knownKey, true)
+}
+
+// ParseTime parses value according to the tag options of its field, e.g.
+// "unix".
+func (d *GeneratedDecoder) ParseTime(value, options string) (time.Time, error) {
+	return defaultDecoder.parseTime(value, splitOptions(options))
+}
+
+// ParseDuration parses value according to the tag options of its field,
+// e.g. "seconds".
+func (d *GeneratedDecoder) ParseDuration(value, options string) (time.Duration, error) {
+	return parseDuration(value, splitOptions(options))
+}
+
+// splitOptions returns the tag options in options, a comma-separated list.
+func splitOptions(options string) tagOptions {
+	_, opts := parseTag("," + options)
+	return opts
+}
@@ -0,0 +1,122 @@
+package httpheader
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// genStruct has hand-written methods marked as generated by httpheader-gen.
+type genStruct struct {
+	A string `header:"X-A"`
+}
+
+func (s genStruct) EncodeHeader(key string, header *http.Header) error {
+	header.Set("X-Generated", s.A)
+	return nil
+}
+
+func (s *genStruct) DecodeHeader(header http.Header, key string) error {
+	s.A = "generated:" + header.Get("X-A")
+	return nil
+}
+
+func (genStruct) HTTPHeaderGenerated() interface{} {
+	return (*genStruct)(nil)
+}
+
+// embedGen embeds genStruct, whose promoted methods don't encode or decode
+// the fields of embedGen.
+type embedGen struct {
+	genStruct
+	B string `header:"X-B"`
+}
+
+// fieldEncoder implements Encoder for its use as a struct field.
+type fieldEncoder struct {
+	A string `header:"X-A"`
+}
+
+func (e fieldEncoder) EncodeHeader(key string, header *http.Header) error {
+	header.Set(key, e.A)
+	return nil
+}
+
+// fieldDecoder implements Decoder for its use as a struct field.
+type fieldDecoder struct {
+	Arg string
+}
+
+func (d *fieldDecoder) DecodeHeader(header http.Header, key string) error {
+	d.Arg = header.Get(key)
+	return nil
+}
+
+// selfDecoder implements Decoder by calling Decode on itself.
+type selfDecoder struct {
+	A string `header:"X-A"`
+}
+
+func (d *selfDecoder) DecodeHeader(header http.Header, key string) error {
+	return Decode(header, d)
+}
+
+func TestHeader_generated(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want http.Header
+	}{
+		{genStruct{A: "a"}, http.Header{"X-Generated": {"a"}}},
+		{&genStruct{A: "a"}, http.Header{"X-Generated": {"a"}}},
+		{embedGen{genStruct{A: "a"}, "b"}, http.Header{"X-A": {"a"}, "X-B": {"b"}}},
+		{fieldEncoder{A: "v"}, http.Header{"X-A": {"v"}}},
+		{struct {
+			G genStruct `header:"X-G"`
+		}{genStruct{A: "a"}}, http.Header{"X-A": {"a"}}},
+		{struct {
+			E *embedGen `header:"X-E"`
+		}{&embedGen{genStruct{A: "a"}, "b"}}, http.Header{"X-A": {"a"}, "X-B": {"b"}}},
+	}
+	for _, tt := range tests {
+		got, err := Header(tt.in)
+		if err != nil {
+			t.Errorf("Header(%#v) returned error: %v", tt.in, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Header(%#v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDecode_generated(t *testing.T) {
+	h := http.Header{"X-A": {"a"}, "X-B": {"b"}, "Arg": {"v"}}
+
+	var g genStruct
+	if err := Decode(h, &g); err != nil || g.A != "generated:a" {
+		t.Errorf("Decode returned %#v, %v, want generated:a", g, err)
+	}
+
+	var e embedGen
+	want := embedGen{genStruct{A: "a"}, "b"}
+	if err := Decode(h, &e); err != nil || !reflect.DeepEqual(e, want) {
+		t.Errorf("Decode returned %#v, %v, want %#v", e, err, want)
+	}
+
+	var f fieldDecoder
+	if err := Decode(h, &f); err != nil || f.Arg != "v" {
+		t.Errorf("Decode returned %#v, %v, want Arg v", f, err)
+	}
+
+	var n struct {
+		G genStruct `header:"X-G"`
+		E embedGen  `header:"X-E"`
+	}
+	if err := Decode(h, &n); err != nil || n.G.A != "a" || n.E.B != "b" {
+		t.Errorf("Decode returned %#v, %v, want fields decoded by reflection", n, err)
+	}
+
+	var s selfDecoder
+	if err := s.DecodeHeader(h, ""); err != nil || s.A != "a" {
+		t.Errorf("DecodeHeader returned %#v, %v, want A a", s, err)
+	}
+}
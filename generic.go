@@ -0,0 +1,34 @@
+package httpheader
+
+import (
+	"net/http"
+	"reflect"
+)
+
+// DecodeAs returns the struct or map of type T decoded from header, using the
+// rules described in the documentation for the Decode function.
+//
+// T must be a struct type or a map type with string keys, so unlike Decode
+// there is no pointer to pass.
+func DecodeAs[T any](header http.Header) (T, error) {
+	var v T
+	if m, ok := structDecoder(&v); ok {
+		return v, m.DecodeHeader(header, "")
+	}
+	err := defaultDecoder.decode(header, nil, reflect.ValueOf(&v).Elem())
+	return v, err
+}
+
+// EncodeTyped returns the http.Header encoding of v, using the rules
+// described in the documentation for the Header function.
+//
+// T must be a struct type, a map type with string keys or a pointer to one of
+// them.
+func EncodeTyped[T any](v T) (http.Header, error) {
+	if m, ok := structEncoder(v); ok {
+		h := make(http.Header)
+		err := m.EncodeHeader("", &h)
+		return h, err
+	}
+	return defaultEncoder.encode(reflect.ValueOf(&v).Elem())
+}
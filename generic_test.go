@@ -0,0 +1,57 @@
+package httpheader
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeAs(t *testing.T) {
+	h := http.Header{"X-Name": []string{"foo"}, "X-Retry": []string{"3"}}
+	type typedStruct struct {
+		Name  string `header:"X-Name"`
+		Retry int    `header:"X-Retry"`
+	}
+	got, err := DecodeAs[typedStruct](h)
+	if err != nil {
+		t.Errorf("DecodeAs returned error: %v", err)
+	}
+	if want := (typedStruct{"foo", 3}); got != want {
+		t.Errorf("DecodeAs returned %#v, want %#v", got, want)
+	}
+
+	if _, err := DecodeAs[typedStruct](http.Header{"X-Retry": []string{"a"}}); err == nil {
+		t.Error("expected DecodeAs to return an error")
+	}
+	if _, err := DecodeAs[int](h); err == nil {
+		t.Error("expected DecodeAs to return an error for non-struct types")
+	}
+}
+
+func TestEncodeTyped(t *testing.T) {
+	s := struct {
+		Name string `header:"X-Name"`
+	}{"foo"}
+	want := http.Header{"X-Name": []string{"foo"}}
+
+	got, err := EncodeTyped(s)
+	if err != nil {
+		t.Errorf("EncodeTyped returned error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("EncodeTyped returned %v, want %v", got, want)
+	}
+
+	if got, _ := EncodeTyped(&s); !reflect.DeepEqual(got, want) {
+		t.Errorf("EncodeTyped(pointer) returned %v, want %v", got, want)
+	}
+	if got, _ := EncodeTyped[interface{}](s); !reflect.DeepEqual(got, want) {
+		t.Errorf("EncodeTyped(interface) returned %v, want %v", got, want)
+	}
+	if got, err := EncodeTyped[*struct{}](nil); err != nil || len(got) != 0 {
+		t.Errorf("EncodeTyped(nil) returned %v, %v, want an empty header", got, err)
+	}
+	if _, err := EncodeTyped(1); err == nil {
+		t.Error("expected EncodeTyped to return an error for non-struct types")
+	}
+}
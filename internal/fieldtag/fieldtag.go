@@ -0,0 +1,157 @@
+// Package fieldtag parses the "header" tags of struct fields. It is shared
+// by the httpheader package and the httpheader-gen command, so that both
+// read the tags the same way.
+package fieldtag
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Kind selects how a struct field is encoded or decoded.
+type Kind int
+
+const (
+	// KindValue fields hold a single value.
+	KindValue Kind = iota
+	// KindSlice fields are slices or arrays encoded as multiple Header values.
+	KindSlice
+	// KindMulti fields are decoded from all the values of a Header field.
+	KindMulti
+	// KindPtr fields are pointers allocated when the Header field is present.
+	KindPtr
+	// KindStruct fields are structs, or pointers to structs, whose fields
+	// can only be resolved at run time.
+	KindStruct
+	// KindHeader fields are http.Header values, or pointers to them.
+	KindHeader
+	// KindPrefix fields are maps holding the Header fields whose names start
+	// with the field name, see the "prefix" option.
+	KindPrefix
+	// KindEncoder fields implement httpheader.Encoder.
+	KindEncoder
+	// KindDecoder fields implement httpheader.Decoder.
+	KindDecoder
+	// KindDecoderAddr fields implement httpheader.Decoder through a pointer
+	// receiver.
+	KindDecoderAddr
+)
+
+// DefaultSeparator separates the values of the "default" option of a list
+// field without a "comma" or "sep" option.
+const DefaultSeparator = ";"
+
+// Options is the string following a comma in a struct field's "header" tag,
+// or the empty string. It does not include the leading comma.
+type Options []string
+
+// Parse splits a struct field's header tag into its name and comma-separated
+// options. The "layout=<layout>" option takes the rest of the tag, as time
+// layouts may contain commas, so it must be the last option.
+func Parse(tag string) (string, Options) {
+	s := strings.Split(tag, ",")
+	for i := 1; i < len(s); i++ {
+		if strings.HasPrefix(s[i], "layout=") {
+			s[i] = strings.Join(s[i:], ",")
+			s = s[:i+1]
+			break
+		}
+	}
+	return s[0], s[1:]
+}
+
+// Contains checks whether the Options contains the specified option.
+func (o Options) Contains(option string) bool {
+	for _, s := range o {
+		if s == option {
+			return true
+		}
+	}
+	return false
+}
+
+// Value returns the value of the option written as "option=value", and
+// whether it is present.
+func (o Options) Value(option string) (string, bool) {
+	for _, s := range o {
+		if len(s) > len(option) && s[len(option)] == '=' && s[:len(option)] == option {
+			return s[len(option)+1:], true
+		}
+	}
+	return "", false
+}
+
+func (o Options) String() string {
+	return strings.Join(o, ",")
+}
+
+// Dup returns the value of the "dup" option in opts, or "" if it is not set.
+// It returns an error if the value doesn't name a duplicate policy.
+func Dup(opts Options) (string, error) {
+	dup, ok := opts.Value("dup")
+	if !ok {
+		return "", nil
+	}
+	switch dup {
+	case "first", "last", "join", "unique":
+		return dup, nil
+	}
+	return "", fmt.Errorf("unknown dup option %q", dup)
+}
+
+// ListSeparator returns the separator set by the "comma" or "sep" option.
+func ListSeparator(opts Options) string {
+	if opts.Contains("comma") {
+		return ","
+	}
+	sep, _ := opts.Value("sep")
+	return sep
+}
+
+// Defaults returns the values of the "default" option in opts, splitting
+// them with the list separator sep, or defaultSep, if list is true.
+func Defaults(opts Options, list bool, sep, defaultSep string) []string {
+	def, ok := opts.Value("default")
+	if !ok {
+		return nil
+	}
+	if !list {
+		return []string{def}
+	}
+	if sep != "" {
+		return SplitList([]string{def}, sep)
+	}
+	return strings.Split(def, defaultSep)
+}
+
+// SplitList splits each of vals into list elements separated by sep,
+// ignoring separators in quoted strings, trimming the optional whitespace
+// around the elements and skipping empty ones as described in RFC 9110
+// section 5.6.1.
+func SplitList(vals []string, sep string) []string {
+	var list []string
+	for _, v := range vals {
+		start, quoted := 0, false
+		for i := 0; i < len(v); i++ {
+			switch {
+			case quoted && v[i] == '\\':
+				i++
+			case v[i] == '"':
+				quoted = !quoted
+			case !quoted && strings.HasPrefix(v[i:], sep):
+				list = appendListElement(list, v[start:i])
+				i += len(sep) - 1
+				start = i + 1
+			}
+		}
+		list = appendListElement(list, v[start:])
+	}
+	return list
+}
+
+func appendListElement(list []string, s string) []string {
+	if s = strings.Trim(s, " \t"); s != "" {
+		list = append(list, s)
+	}
+	return list
+}
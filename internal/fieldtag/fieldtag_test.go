@@ -0,0 +1,39 @@
+package fieldtag
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDefaults(t *testing.T) {
+	tests := []struct {
+		tag  string
+		list bool
+		want []string
+	}{
+		{"X,omitempty", true, nil},
+		{"X,default=a;b", false, []string{"a;b"}},
+		{"X,default=a;b", true, []string{"a", "b"}},
+		{`X,sep=;,default="a;b"; c`, true, []string{`"a;b"`, "c"}},
+	}
+	for _, tt := range tests {
+		_, opts := Parse(tt.tag)
+		got := Defaults(opts, tt.list, ListSeparator(opts), DefaultSeparator)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Defaults(%q, %v) returned %q, want %q", tt.tag, tt.list, got, tt.want)
+		}
+	}
+}
+
+func TestDup(t *testing.T) {
+	for _, tag := range []string{"X", "X,dup=first", "X,dup=last", "X,dup=join", "X,dup=unique"} {
+		_, opts := Parse(tag)
+		if _, err := Dup(opts); err != nil {
+			t.Errorf("Dup(%q) returned error %v", tag, err)
+		}
+	}
+	_, opts := Parse("X,dup=uniqe")
+	if _, err := Dup(opts); err == nil {
+		t.Errorf("Dup(%q) returned no error", "X,dup=uniqe")
+	}
+}
@@ -0,0 +1,2538 @@
+// Code generated by httpheader-gen; DO NOT EDIT.
+
+package gentest
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mozillazg/go-httpheader"
+)
+
+// EncodeHeader implements the httpheader.Encoder interface. It encodes
+// the fields of s into header as described in the documentation for
+// httpheader.Header. key is ignored.
+func (s Options) EncodeHeader(key string, header *http.Header) error {
+	if *header == nil {
+		*header = make(http.Header)
+	}
+	e := httpheader.GeneratedEncoder{Header: *header, Struct: "Options"}
+	// Name
+	{
+		if err := e.Add("Name", "Name", s.Name); err != nil {
+			return err
+		}
+	}
+	// Alias
+	if len(s.Alias) > 0 {
+		if err := e.Add("Alias", "X-Alias", s.Alias); err != nil {
+			return err
+		}
+	}
+	// Flag
+	{
+		if err := e.Add("Flag", "X-Flag", strconv.FormatBool(s.Flag)); err != nil {
+			return err
+		}
+	}
+	// IntFlag
+	{
+		v1 := "0"
+		if s.IntFlag {
+			v1 = "1"
+		}
+		if err := e.Add("IntFlag", "X-Int-Flag", v1); err != nil {
+			return err
+		}
+	}
+	// Count
+	if s.Count != 0 {
+		if err := e.Add("Count", "X-Count", strconv.FormatInt(int64(s.Count), 10)); err != nil {
+			return err
+		}
+	}
+	// Small
+	{
+		if err := e.Add("Small", "X-Small", strconv.FormatInt(int64(s.Small), 10)); err != nil {
+			return err
+		}
+	}
+	// Port
+	{
+		if err := e.Add("Port", "X-Port", strconv.FormatUint(uint64(s.Port), 10)); err != nil {
+			return err
+		}
+	}
+	// Size
+	{
+		if err := e.Add("Size", "X-Size", strconv.FormatUint(s.Size, 10)); err != nil {
+			return err
+		}
+	}
+	// Ratio
+	{
+		if err := e.Add("Ratio", "X-Ratio", strconv.FormatFloat(s.Ratio, 'g', -1, 64)); err != nil {
+			return err
+		}
+	}
+	// Weight
+	if s.Weight != 0 {
+		if err := e.Add("Weight", "X-Weight", strconv.FormatFloat(float64(s.Weight), 'g', -1, 32)); err != nil {
+			return err
+		}
+	}
+	// Level
+	{
+		if err := e.Add("Level", "X-Level", strconv.FormatInt(int64(s.Level), 10)); err != nil {
+			return err
+		}
+	}
+	// Mode
+	{
+		if err := e.Add("Mode", "X-Mode", fmt.Sprint(s.Mode)); err != nil {
+			return err
+		}
+	}
+	// Color
+	{
+		b2, err := s.Color.MarshalText()
+		if err != nil {
+			return err
+		}
+		if err := e.Add("Color", "X-Color", string(b2)); err != nil {
+			return err
+		}
+	}
+	// Token
+	{
+		v3 := ""
+		if s.Token != nil {
+			v3 = (*s.Token)
+		}
+		if err := e.Add("Token", "X-Token", v3); err != nil {
+			return err
+		}
+	}
+	// Retries
+	if s.Retries != nil {
+		v4 := ""
+		if s.Retries != nil {
+			v4 = strconv.FormatInt(int64((*s.Retries)), 10)
+		}
+		if err := e.Add("Retries", "X-Retries", v4); err != nil {
+			return err
+		}
+	}
+	// Modified
+	{
+		if err := e.Add("Modified", "Modified", e.FormatTime(s.Modified, "")); err != nil {
+			return err
+		}
+	}
+	// Expires
+	if !s.Expires.IsZero() {
+		if err := e.Add("Expires", "X-Expires", e.FormatTime(s.Expires, "unix,omitempty")); err != nil {
+			return err
+		}
+	}
+	// Created
+	{
+		if err := e.Add("Created", "X-Created", e.FormatTime(s.Created, "rfc3339")); err != nil {
+			return err
+		}
+	}
+	// Day
+	{
+		if err := e.Add("Day", "X-Day", e.FormatTime(s.Day, "layout=2006-01-02")); err != nil {
+			return err
+		}
+	}
+	// Timeout
+	{
+		if err := e.Add("Timeout", "X-Timeout", e.FormatDuration(s.Timeout, "")); err != nil {
+			return err
+		}
+	}
+	// Delay
+	if s.Delay != 0 {
+		if err := e.Add("Delay", "X-Delay", e.FormatDuration(s.Delay, "seconds,omitempty")); err != nil {
+			return err
+		}
+	}
+	// Backoff
+	{
+		if err := e.Add("Backoff", "X-Backoff", strconv.FormatInt(int64(s.Backoff), 10)); err != nil {
+			return err
+		}
+	}
+	// RequestID
+	{
+		if err := e.Add("RequestID", "X-Request-Id", s.RequestID); err != nil {
+			return err
+		}
+	}
+	// Proto
+	{
+		if err := e.Add("Proto", "X-Forwarded-Proto", s.Proto); err != nil {
+			return err
+		}
+	}
+	// Via
+	{
+		if err := e.Add("Via", "Via", s.Via); err != nil {
+			return err
+		}
+	}
+	// Hops
+	{
+		v5 := ""
+		if s.Hops != nil {
+			v5 = strconv.FormatInt(int64((*s.Hops)), 10)
+		}
+		if err := e.Add("Hops", "X-Hops", v5); err != nil {
+			return err
+		}
+	}
+	// Part
+	{
+		v6 := ""
+		if s.Part != nil {
+			v6 = (*s.Part)
+		}
+		if err := e.Add("Part", "X-Part", v6); err != nil {
+			return err
+		}
+	}
+	// Seen
+	{
+		if err := e.Add("Seen", "X-Seen", e.FormatTime(s.Seen, "unix,dup=last")); err != nil {
+			return err
+		}
+	}
+	// Extra
+	{
+		if err := e.AddHeader("Extra", s.Extra, true); err != nil {
+			return err
+		}
+	}
+	// Meta
+	{
+		for k7, v8 := range s.Meta {
+			if err := e.Add("Meta", http.CanonicalHeaderKey("X-Meta-"+k7), v8); err != nil {
+				return err
+			}
+		}
+	}
+	// Tags
+	{
+		for k9, v10 := range s.Tags {
+			for _, s11 := range v10 {
+				if err := e.Add("Tags", http.CanonicalHeaderKey("X-Tag-"+k9), s11); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	// Object
+	{
+		for k12, v13 := range s.Object {
+			if err := e.Add("Object", "x-cos-meta-"+k12, v13); err != nil {
+				return err
+			}
+		}
+	}
+	// Lower
+	if len(s.Lower) > 0 {
+		if err := e.Add("Lower", "x-lower", s.Lower); err != nil {
+			return err
+		}
+	}
+	// Inner.Region
+	{
+		if err := e.Add("Inner.Region", "X-Region", s.Inner.Region); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DecodeHeader implements the httpheader.Decoder interface. It decodes
+// header into the fields of s as described in the documentation for
+// httpheader.Decode. key is ignored.
+func (s *Options) DecodeHeader(header http.Header, key string) error {
+	d := httpheader.GeneratedDecoder{Header: header, Struct: "Options"}
+	// Name
+	{
+		vals := header["Name"]
+		if len(vals) > 0 {
+			s.Name = vals[0]
+		}
+	}
+	// Alias
+	{
+		vals := header["X-Alias"]
+		if len(vals) > 0 && vals[0] != "" {
+			s.Alias = vals[0]
+		}
+	}
+	// Flag
+	{
+		vals := header["X-Flag"]
+		if len(vals) > 0 {
+			s.Flag = vals[0] != "false"
+		}
+	}
+	// IntFlag
+	{
+		vals := header["X-Int-Flag"]
+		if len(vals) > 0 {
+			s.IntFlag = vals[0] != "0"
+		}
+	}
+	// Count
+	{
+		vals := header["X-Count"]
+		if len(vals) > 0 && vals[0] != "" {
+			n14, err := strconv.ParseInt(vals[0], 10, 64)
+			if err != nil {
+				return d.Error("Count", "X-Count", vals[:1], err)
+			}
+			s.Count = int(n14)
+		}
+	}
+	// Small
+	{
+		vals := header["X-Small"]
+		if len(vals) > 0 {
+			n15, err := strconv.ParseInt(vals[0], 10, 8)
+			if err != nil {
+				return d.Error("Small", "X-Small", vals[:1], err)
+			}
+			s.Small = int8(n15)
+		}
+	}
+	// Port
+	{
+		vals := header["X-Port"]
+		if len(vals) > 0 {
+			n16, err := strconv.ParseUint(vals[0], 10, 16)
+			if err != nil {
+				return d.Error("Port", "X-Port", vals[:1], err)
+			}
+			s.Port = uint16(n16)
+		}
+	}
+	// Size
+	{
+		vals := header["X-Size"]
+		if len(vals) == 0 {
+			vals = []string{"512"}
+		}
+		if len(vals) > 0 {
+			n17, err := strconv.ParseUint(vals[0], 10, 64)
+			if err != nil {
+				return d.Error("Size", "X-Size", vals[:1], err)
+			}
+			s.Size = n17
+		}
+	}
+	// Ratio
+	{
+		vals := header["X-Ratio"]
+		if len(vals) > 0 {
+			n18, err := strconv.ParseFloat(vals[0], 64)
+			if err != nil {
+				return d.Error("Ratio", "X-Ratio", vals[:1], err)
+			}
+			s.Ratio = n18
+		}
+	}
+	// Weight
+	{
+		vals := header["X-Weight"]
+		if len(vals) > 0 && vals[0] != "" {
+			n19, err := strconv.ParseFloat(vals[0], 32)
+			if err != nil {
+				return d.Error("Weight", "X-Weight", vals[:1], err)
+			}
+			s.Weight = float32(n19)
+		}
+	}
+	// Level
+	{
+		vals := header["X-Level"]
+		if len(vals) > 0 {
+			n20, err := strconv.ParseInt(vals[0], 10, 64)
+			if err != nil {
+				return d.Error("Level", "X-Level", vals[:1], err)
+			}
+			s.Level = Level(n20)
+		}
+	}
+	// Mode
+	{
+		vals := header["X-Mode"]
+		if len(vals) > 0 {
+			s.Mode = Mode(vals[0])
+		}
+	}
+	// Color
+	{
+		vals := header["X-Color"]
+		if len(vals) > 0 && vals[0] != "" {
+			if err := s.Color.UnmarshalText([]byte(vals[0])); err != nil {
+				return d.Error("Color", "X-Color", vals[:1], err)
+			}
+		}
+	}
+	// Token
+	{
+		vals := header["X-Token"]
+		if len(vals) > 0 {
+			p21 := new(string)
+			(*p21) = vals[0]
+			s.Token = p21
+		}
+	}
+	// Retries
+	{
+		vals := header["X-Retries"]
+		if len(vals) > 0 && vals[0] != "" {
+			p22 := new(int)
+			n23, err := strconv.ParseInt(vals[0], 10, 64)
+			if err != nil {
+				return d.Error("Retries", "X-Retries", vals, err)
+			}
+			(*p22) = int(n23)
+			s.Retries = p22
+		}
+	}
+	// Modified
+	{
+		vals := header["Modified"]
+		if len(vals) > 0 {
+			t24, err := d.ParseTime(vals[0], "")
+			if err != nil {
+				return d.Error("Modified", "Modified", vals, err)
+			}
+			s.Modified = t24
+		}
+	}
+	// Expires
+	{
+		vals := header["X-Expires"]
+		if len(vals) > 0 && vals[0] != "" {
+			t25, err := d.ParseTime(vals[0], "unix,omitempty")
+			if err != nil {
+				return d.Error("Expires", "X-Expires", vals, err)
+			}
+			s.Expires = t25
+		}
+	}
+	// Created
+	{
+		vals := header["X-Created"]
+		if len(vals) > 0 {
+			t26, err := d.ParseTime(vals[0], "rfc3339")
+			if err != nil {
+				return d.Error("Created", "X-Created", vals, err)
+			}
+			s.Created = t26
+		}
+	}
+	// Day
+	{
+		vals := header["X-Day"]
+		if len(vals) > 0 {
+			t27, err := d.ParseTime(vals[0], "layout=2006-01-02")
+			if err != nil {
+				return d.Error("Day", "X-Day", vals, err)
+			}
+			s.Day = t27
+		}
+	}
+	// Timeout
+	{
+		vals := header["X-Timeout"]
+		if len(vals) > 0 {
+			n28, err := d.ParseDuration(vals[0], "")
+			if err != nil {
+				return d.Error("Timeout", "X-Timeout", vals[:1], err)
+			}
+			s.Timeout = n28
+		}
+	}
+	// Delay
+	{
+		vals := header["X-Delay"]
+		if len(vals) > 0 && vals[0] != "" {
+			n29, err := d.ParseDuration(vals[0], "seconds,omitempty")
+			if err != nil {
+				return d.Error("Delay", "X-Delay", vals[:1], err)
+			}
+			s.Delay = n29
+		}
+	}
+	// Backoff
+	{
+		vals := header["X-Backoff"]
+		if len(vals) > 0 {
+			n30, err := strconv.ParseInt(vals[0], 10, 64)
+			if err != nil {
+				return d.Error("Backoff", "X-Backoff", vals[:1], err)
+			}
+			s.Backoff = Backoff(n30)
+		}
+	}
+	// RequestID
+	if !d.Present("X-Request-Id", false) {
+		d.Missing("RequestID", "X-Request-Id")
+	} else {
+		vals := header["X-Request-Id"]
+		if len(vals) > 0 {
+			s.RequestID = vals[0]
+		}
+	}
+	// Proto
+	{
+		vals := header["X-Forwarded-Proto"]
+		vals, err := d.Duplicates("Proto", "X-Forwarded-Proto", vals, "dup=unique")
+		if err != nil {
+			return err
+		}
+		if len(vals) > 0 {
+			s.Proto = vals[0]
+		}
+	}
+	// Via
+	{
+		vals := header["Via"]
+		vals, err := d.Duplicates("Via", "Via", vals, "dup=join,sep=;")
+		if err != nil {
+			return err
+		}
+		if len(vals) > 0 {
+			s.Via = vals[0]
+		}
+	}
+	// Hops
+	{
+		vals := header["X-Hops"]
+		raw := vals
+		vals, err := d.Duplicates("Hops", "X-Hops", vals, "dup=last")
+		if err != nil {
+			return err
+		}
+		if len(vals) > 0 {
+			p31 := new(int)
+			n32, err := strconv.ParseInt(vals[0], 10, 64)
+			if err != nil {
+				return d.Error("Hops", "X-Hops", raw, err)
+			}
+			(*p31) = int(n32)
+			s.Hops = p31
+		}
+	}
+	// Part
+	{
+		vals := header["X-Part"]
+		vals, err := d.Duplicates("Part", "X-Part", vals, "dup=last,sep=;")
+		if err != nil {
+			return err
+		}
+		if len(vals) > 0 {
+			p33 := new(string)
+			(*p33) = vals[0]
+			s.Part = p33
+		}
+	}
+	// Seen
+	{
+		vals := header["X-Seen"]
+		raw := vals
+		vals, err := d.Duplicates("Seen", "X-Seen", vals, "unix,dup=last")
+		if err != nil {
+			return err
+		}
+		if len(vals) > 0 {
+			t34, err := d.ParseTime(vals[0], "unix,dup=last")
+			if err != nil {
+				return d.Error("Seen", "X-Seen", raw, err)
+			}
+			s.Seen = t34
+		}
+	}
+	// Extra
+	{
+		if rest35 := d.Rest([]string{"Name", "X-Alias", "X-Flag", "X-Int-Flag", "X-Count", "X-Small", "X-Port", "X-Size", "X-Ratio", "X-Weight", "X-Level", "X-Mode", "X-Color", "X-Token", "X-Retries", "Modified", "X-Expires", "X-Created", "X-Day", "X-Timeout", "X-Delay", "X-Backoff", "X-Request-Id", "X-Forwarded-Proto", "Via", "X-Hops", "X-Part", "X-Seen", "x-lower", "X-Region"}, []string{"X-Meta-", "X-Tag-", "x-cos-meta-"}); rest35 != nil {
+			s.Extra = rest35
+		}
+	}
+	// Meta
+	{
+		d.Prefixed("X-Meta-", func(k36 string, vs37 []string) {
+			if s.Meta == nil {
+				s.Meta = make(map[string]string)
+			}
+			s.Meta[k36] = vs37[0]
+		})
+	}
+	// Tags
+	{
+		d.Prefixed("X-Tag-", func(k38 string, vs39 []string) {
+			if s.Tags == nil {
+				s.Tags = make(map[string][]string)
+			}
+			v40 := make([]string, len(vs39))
+			for i41, s42 := range vs39 {
+				v40[i41] = s42
+			}
+			s.Tags[k38] = v40
+		})
+	}
+	// Object
+	{
+		d.Prefixed("x-cos-meta-", func(k43 string, vs44 []string) {
+			if s.Object == nil {
+				s.Object = make(map[string]string)
+			}
+			s.Object[k43] = vs44[0]
+		})
+	}
+	// Lower
+	{
+		vals := header["x-lower"]
+		if len(vals) > 0 && vals[0] != "" {
+			s.Lower = vals[0]
+		}
+	}
+	// Inner.Region
+	{
+		vals := header["X-Region"]
+		if len(vals) == 0 {
+			vals = []string{"eu"}
+		}
+		if len(vals) > 0 {
+			s.Inner.Region = vals[0]
+		}
+	}
+	return d.Err()
+}
+
+// HTTPHeaderGenerated marks the methods of Options as generated by
+// httpheader-gen, so that httpheader.Header and httpheader.Decode call them.
+func (Options) HTTPHeaderGenerated() interface{} {
+	return (*Options)(nil)
+}
+
+// EncodeHeader implements the httpheader.Encoder interface. It encodes
+// the fields of s into header as described in the documentation for
+// httpheader.Header. key is ignored.
+func (s Nested) EncodeHeader(key string, header *http.Header) error {
+	if *header == nil {
+		*header = make(http.Header)
+	}
+	e := httpheader.GeneratedEncoder{Header: *header, Struct: "Nested"}
+	// Auth
+	{
+		if err := e.Encode("Auth", "Authorization", s.Auth); err != nil {
+			return err
+		}
+	}
+	// Limits.Max
+	{
+		if err := e.Add("Limits.Max", "X-Limit-Max", strconv.FormatInt(int64(s.Limits.Max), 10)); err != nil {
+			return err
+		}
+	}
+	// Limits.Min
+	if s.Limits.Min != 0 {
+		if err := e.Add("Limits.Min", "X-Limit-Min", strconv.FormatInt(int64(s.Limits.Min), 10)); err != nil {
+			return err
+		}
+	}
+	// Page
+	{
+		if err := e.Encode("Page", "X-Page", s.Page); err != nil {
+			return err
+		}
+	}
+	// Options.Name
+	{
+		if err := e.Add("Options.Name", "Name", s.Options.Name); err != nil {
+			return err
+		}
+	}
+	// Options.Alias
+	if len(s.Options.Alias) > 0 {
+		if err := e.Add("Options.Alias", "X-Alias", s.Options.Alias); err != nil {
+			return err
+		}
+	}
+	// Options.Flag
+	{
+		if err := e.Add("Options.Flag", "X-Flag", strconv.FormatBool(s.Options.Flag)); err != nil {
+			return err
+		}
+	}
+	// Options.IntFlag
+	{
+		v1 := "0"
+		if s.Options.IntFlag {
+			v1 = "1"
+		}
+		if err := e.Add("Options.IntFlag", "X-Int-Flag", v1); err != nil {
+			return err
+		}
+	}
+	// Options.Count
+	if s.Options.Count != 0 {
+		if err := e.Add("Options.Count", "X-Count", strconv.FormatInt(int64(s.Options.Count), 10)); err != nil {
+			return err
+		}
+	}
+	// Options.Small
+	{
+		if err := e.Add("Options.Small", "X-Small", strconv.FormatInt(int64(s.Options.Small), 10)); err != nil {
+			return err
+		}
+	}
+	// Options.Port
+	{
+		if err := e.Add("Options.Port", "X-Port", strconv.FormatUint(uint64(s.Options.Port), 10)); err != nil {
+			return err
+		}
+	}
+	// Options.Size
+	{
+		if err := e.Add("Options.Size", "X-Size", strconv.FormatUint(s.Options.Size, 10)); err != nil {
+			return err
+		}
+	}
+	// Options.Ratio
+	{
+		if err := e.Add("Options.Ratio", "X-Ratio", strconv.FormatFloat(s.Options.Ratio, 'g', -1, 64)); err != nil {
+			return err
+		}
+	}
+	// Options.Weight
+	if s.Options.Weight != 0 {
+		if err := e.Add("Options.Weight", "X-Weight", strconv.FormatFloat(float64(s.Options.Weight), 'g', -1, 32)); err != nil {
+			return err
+		}
+	}
+	// Options.Level
+	{
+		if err := e.Add("Options.Level", "X-Level", strconv.FormatInt(int64(s.Options.Level), 10)); err != nil {
+			return err
+		}
+	}
+	// Options.Mode
+	{
+		if err := e.Add("Options.Mode", "X-Mode", fmt.Sprint(s.Options.Mode)); err != nil {
+			return err
+		}
+	}
+	// Options.Color
+	{
+		b2, err := s.Options.Color.MarshalText()
+		if err != nil {
+			return err
+		}
+		if err := e.Add("Options.Color", "X-Color", string(b2)); err != nil {
+			return err
+		}
+	}
+	// Options.Token
+	{
+		v3 := ""
+		if s.Options.Token != nil {
+			v3 = (*s.Options.Token)
+		}
+		if err := e.Add("Options.Token", "X-Token", v3); err != nil {
+			return err
+		}
+	}
+	// Options.Retries
+	if s.Options.Retries != nil {
+		v4 := ""
+		if s.Options.Retries != nil {
+			v4 = strconv.FormatInt(int64((*s.Options.Retries)), 10)
+		}
+		if err := e.Add("Options.Retries", "X-Retries", v4); err != nil {
+			return err
+		}
+	}
+	// Options.Modified
+	{
+		if err := e.Add("Options.Modified", "Modified", e.FormatTime(s.Options.Modified, "")); err != nil {
+			return err
+		}
+	}
+	// Options.Expires
+	if !s.Options.Expires.IsZero() {
+		if err := e.Add("Options.Expires", "X-Expires", e.FormatTime(s.Options.Expires, "unix,omitempty")); err != nil {
+			return err
+		}
+	}
+	// Options.Created
+	{
+		if err := e.Add("Options.Created", "X-Created", e.FormatTime(s.Options.Created, "rfc3339")); err != nil {
+			return err
+		}
+	}
+	// Options.Day
+	{
+		if err := e.Add("Options.Day", "X-Day", e.FormatTime(s.Options.Day, "layout=2006-01-02")); err != nil {
+			return err
+		}
+	}
+	// Options.Timeout
+	{
+		if err := e.Add("Options.Timeout", "X-Timeout", e.FormatDuration(s.Options.Timeout, "")); err != nil {
+			return err
+		}
+	}
+	// Options.Delay
+	if s.Options.Delay != 0 {
+		if err := e.Add("Options.Delay", "X-Delay", e.FormatDuration(s.Options.Delay, "seconds,omitempty")); err != nil {
+			return err
+		}
+	}
+	// Options.Backoff
+	{
+		if err := e.Add("Options.Backoff", "X-Backoff", strconv.FormatInt(int64(s.Options.Backoff), 10)); err != nil {
+			return err
+		}
+	}
+	// Options.RequestID
+	{
+		if err := e.Add("Options.RequestID", "X-Request-Id", s.Options.RequestID); err != nil {
+			return err
+		}
+	}
+	// Options.Proto
+	{
+		if err := e.Add("Options.Proto", "X-Forwarded-Proto", s.Options.Proto); err != nil {
+			return err
+		}
+	}
+	// Options.Via
+	{
+		if err := e.Add("Options.Via", "Via", s.Options.Via); err != nil {
+			return err
+		}
+	}
+	// Options.Hops
+	{
+		v5 := ""
+		if s.Options.Hops != nil {
+			v5 = strconv.FormatInt(int64((*s.Options.Hops)), 10)
+		}
+		if err := e.Add("Options.Hops", "X-Hops", v5); err != nil {
+			return err
+		}
+	}
+	// Options.Part
+	{
+		v6 := ""
+		if s.Options.Part != nil {
+			v6 = (*s.Options.Part)
+		}
+		if err := e.Add("Options.Part", "X-Part", v6); err != nil {
+			return err
+		}
+	}
+	// Options.Seen
+	{
+		if err := e.Add("Options.Seen", "X-Seen", e.FormatTime(s.Options.Seen, "unix,dup=last")); err != nil {
+			return err
+		}
+	}
+	// Options.Extra
+	{
+		if err := e.AddHeader("Options.Extra", s.Options.Extra, true); err != nil {
+			return err
+		}
+	}
+	// Options.Meta
+	{
+		for k7, v8 := range s.Options.Meta {
+			if err := e.Add("Options.Meta", http.CanonicalHeaderKey("X-Meta-"+k7), v8); err != nil {
+				return err
+			}
+		}
+	}
+	// Options.Tags
+	{
+		for k9, v10 := range s.Options.Tags {
+			for _, s11 := range v10 {
+				if err := e.Add("Options.Tags", http.CanonicalHeaderKey("X-Tag-"+k9), s11); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	// Options.Object
+	{
+		for k12, v13 := range s.Options.Object {
+			if err := e.Add("Options.Object", "x-cos-meta-"+k12, v13); err != nil {
+				return err
+			}
+		}
+	}
+	// Options.Lower
+	if len(s.Options.Lower) > 0 {
+		if err := e.Add("Options.Lower", "x-lower", s.Options.Lower); err != nil {
+			return err
+		}
+	}
+	// Options.Inner.Region
+	{
+		if err := e.Add("Options.Inner.Region", "X-Region", s.Options.Inner.Region); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DecodeHeader implements the httpheader.Decoder interface. It decodes
+// header into the fields of s as described in the documentation for
+// httpheader.Decode. key is ignored.
+func (s *Nested) DecodeHeader(header http.Header, key string) error {
+	d := httpheader.GeneratedDecoder{Header: header, Struct: "Nested"}
+	// Auth
+	{
+		if err := s.Auth.DecodeHeader(header, "Authorization"); err != nil {
+			return d.Error("Auth", "Authorization", header["Authorization"], err)
+		}
+	}
+	// Limits
+	{
+		vals := header["X-Limit"]
+		if len(vals) > 0 && vals[0] != "" {
+			// Limits.Max
+			{
+				vals := header["X-Limit-Max"]
+				if len(vals) > 0 {
+					n14, err := strconv.ParseInt(vals[0], 10, 64)
+					if err != nil {
+						return d.Error("Limits.Max", "X-Limit-Max", vals[:1], err)
+					}
+					s.Limits.Max = int(n14)
+				}
+			}
+			// Limits.Min
+			{
+				vals := header["X-Limit-Min"]
+				if len(vals) > 0 && vals[0] != "" {
+					n15, err := strconv.ParseInt(vals[0], 10, 64)
+					if err != nil {
+						return d.Error("Limits.Min", "X-Limit-Min", vals[:1], err)
+					}
+					s.Limits.Min = int(n15)
+				}
+			}
+		}
+	}
+	// Page
+	{
+		if err := s.Page.DecodeHeader(header, "X-Page"); err != nil {
+			return d.Error("Page", "X-Page", header["X-Page"], err)
+		}
+	}
+	// Options.Name
+	{
+		vals := header["Name"]
+		if len(vals) > 0 {
+			s.Options.Name = vals[0]
+		}
+	}
+	// Options.Alias
+	{
+		vals := header["X-Alias"]
+		if len(vals) > 0 && vals[0] != "" {
+			s.Options.Alias = vals[0]
+		}
+	}
+	// Options.Flag
+	{
+		vals := header["X-Flag"]
+		if len(vals) > 0 {
+			s.Options.Flag = vals[0] != "false"
+		}
+	}
+	// Options.IntFlag
+	{
+		vals := header["X-Int-Flag"]
+		if len(vals) > 0 {
+			s.Options.IntFlag = vals[0] != "0"
+		}
+	}
+	// Options.Count
+	{
+		vals := header["X-Count"]
+		if len(vals) > 0 && vals[0] != "" {
+			n16, err := strconv.ParseInt(vals[0], 10, 64)
+			if err != nil {
+				return d.Error("Options.Count", "X-Count", vals[:1], err)
+			}
+			s.Options.Count = int(n16)
+		}
+	}
+	// Options.Small
+	{
+		vals := header["X-Small"]
+		if len(vals) > 0 {
+			n17, err := strconv.ParseInt(vals[0], 10, 8)
+			if err != nil {
+				return d.Error("Options.Small", "X-Small", vals[:1], err)
+			}
+			s.Options.Small = int8(n17)
+		}
+	}
+	// Options.Port
+	{
+		vals := header["X-Port"]
+		if len(vals) > 0 {
+			n18, err := strconv.ParseUint(vals[0], 10, 16)
+			if err != nil {
+				return d.Error("Options.Port", "X-Port", vals[:1], err)
+			}
+			s.Options.Port = uint16(n18)
+		}
+	}
+	// Options.Size
+	{
+		vals := header["X-Size"]
+		if len(vals) == 0 {
+			vals = []string{"512"}
+		}
+		if len(vals) > 0 {
+			n19, err := strconv.ParseUint(vals[0], 10, 64)
+			if err != nil {
+				return d.Error("Options.Size", "X-Size", vals[:1], err)
+			}
+			s.Options.Size = n19
+		}
+	}
+	// Options.Ratio
+	{
+		vals := header["X-Ratio"]
+		if len(vals) > 0 {
+			n20, err := strconv.ParseFloat(vals[0], 64)
+			if err != nil {
+				return d.Error("Options.Ratio", "X-Ratio", vals[:1], err)
+			}
+			s.Options.Ratio = n20
+		}
+	}
+	// Options.Weight
+	{
+		vals := header["X-Weight"]
+		if len(vals) > 0 && vals[0] != "" {
+			n21, err := strconv.ParseFloat(vals[0], 32)
+			if err != nil {
+				return d.Error("Options.Weight", "X-Weight", vals[:1], err)
+			}
+			s.Options.Weight = float32(n21)
+		}
+	}
+	// Options.Level
+	{
+		vals := header["X-Level"]
+		if len(vals) > 0 {
+			n22, err := strconv.ParseInt(vals[0], 10, 64)
+			if err != nil {
+				return d.Error("Options.Level", "X-Level", vals[:1], err)
+			}
+			s.Options.Level = Level(n22)
+		}
+	}
+	// Options.Mode
+	{
+		vals := header["X-Mode"]
+		if len(vals) > 0 {
+			s.Options.Mode = Mode(vals[0])
+		}
+	}
+	// Options.Color
+	{
+		vals := header["X-Color"]
+		if len(vals) > 0 && vals[0] != "" {
+			if err := s.Options.Color.UnmarshalText([]byte(vals[0])); err != nil {
+				return d.Error("Options.Color", "X-Color", vals[:1], err)
+			}
+		}
+	}
+	// Options.Token
+	{
+		vals := header["X-Token"]
+		if len(vals) > 0 {
+			p23 := new(string)
+			(*p23) = vals[0]
+			s.Options.Token = p23
+		}
+	}
+	// Options.Retries
+	{
+		vals := header["X-Retries"]
+		if len(vals) > 0 && vals[0] != "" {
+			p24 := new(int)
+			n25, err := strconv.ParseInt(vals[0], 10, 64)
+			if err != nil {
+				return d.Error("Options.Retries", "X-Retries", vals, err)
+			}
+			(*p24) = int(n25)
+			s.Options.Retries = p24
+		}
+	}
+	// Options.Modified
+	{
+		vals := header["Modified"]
+		if len(vals) > 0 {
+			t26, err := d.ParseTime(vals[0], "")
+			if err != nil {
+				return d.Error("Options.Modified", "Modified", vals, err)
+			}
+			s.Options.Modified = t26
+		}
+	}
+	// Options.Expires
+	{
+		vals := header["X-Expires"]
+		if len(vals) > 0 && vals[0] != "" {
+			t27, err := d.ParseTime(vals[0], "unix,omitempty")
+			if err != nil {
+				return d.Error("Options.Expires", "X-Expires", vals, err)
+			}
+			s.Options.Expires = t27
+		}
+	}
+	// Options.Created
+	{
+		vals := header["X-Created"]
+		if len(vals) > 0 {
+			t28, err := d.ParseTime(vals[0], "rfc3339")
+			if err != nil {
+				return d.Error("Options.Created", "X-Created", vals, err)
+			}
+			s.Options.Created = t28
+		}
+	}
+	// Options.Day
+	{
+		vals := header["X-Day"]
+		if len(vals) > 0 {
+			t29, err := d.ParseTime(vals[0], "layout=2006-01-02")
+			if err != nil {
+				return d.Error("Options.Day", "X-Day", vals, err)
+			}
+			s.Options.Day = t29
+		}
+	}
+	// Options.Timeout
+	{
+		vals := header["X-Timeout"]
+		if len(vals) > 0 {
+			n30, err := d.ParseDuration(vals[0], "")
+			if err != nil {
+				return d.Error("Options.Timeout", "X-Timeout", vals[:1], err)
+			}
+			s.Options.Timeout = n30
+		}
+	}
+	// Options.Delay
+	{
+		vals := header["X-Delay"]
+		if len(vals) > 0 && vals[0] != "" {
+			n31, err := d.ParseDuration(vals[0], "seconds,omitempty")
+			if err != nil {
+				return d.Error("Options.Delay", "X-Delay", vals[:1], err)
+			}
+			s.Options.Delay = n31
+		}
+	}
+	// Options.Backoff
+	{
+		vals := header["X-Backoff"]
+		if len(vals) > 0 {
+			n32, err := strconv.ParseInt(vals[0], 10, 64)
+			if err != nil {
+				return d.Error("Options.Backoff", "X-Backoff", vals[:1], err)
+			}
+			s.Options.Backoff = Backoff(n32)
+		}
+	}
+	// Options.RequestID
+	if !d.Present("X-Request-Id", false) {
+		d.Missing("Options.RequestID", "X-Request-Id")
+	} else {
+		vals := header["X-Request-Id"]
+		if len(vals) > 0 {
+			s.Options.RequestID = vals[0]
+		}
+	}
+	// Options.Proto
+	{
+		vals := header["X-Forwarded-Proto"]
+		vals, err := d.Duplicates("Options.Proto", "X-Forwarded-Proto", vals, "dup=unique")
+		if err != nil {
+			return err
+		}
+		if len(vals) > 0 {
+			s.Options.Proto = vals[0]
+		}
+	}
+	// Options.Via
+	{
+		vals := header["Via"]
+		vals, err := d.Duplicates("Options.Via", "Via", vals, "dup=join,sep=;")
+		if err != nil {
+			return err
+		}
+		if len(vals) > 0 {
+			s.Options.Via = vals[0]
+		}
+	}
+	// Options.Hops
+	{
+		vals := header["X-Hops"]
+		raw := vals
+		vals, err := d.Duplicates("Options.Hops", "X-Hops", vals, "dup=last")
+		if err != nil {
+			return err
+		}
+		if len(vals) > 0 {
+			p33 := new(int)
+			n34, err := strconv.ParseInt(vals[0], 10, 64)
+			if err != nil {
+				return d.Error("Options.Hops", "X-Hops", raw, err)
+			}
+			(*p33) = int(n34)
+			s.Options.Hops = p33
+		}
+	}
+	// Options.Part
+	{
+		vals := header["X-Part"]
+		vals, err := d.Duplicates("Options.Part", "X-Part", vals, "dup=last,sep=;")
+		if err != nil {
+			return err
+		}
+		if len(vals) > 0 {
+			p35 := new(string)
+			(*p35) = vals[0]
+			s.Options.Part = p35
+		}
+	}
+	// Options.Seen
+	{
+		vals := header["X-Seen"]
+		raw := vals
+		vals, err := d.Duplicates("Options.Seen", "X-Seen", vals, "unix,dup=last")
+		if err != nil {
+			return err
+		}
+		if len(vals) > 0 {
+			t36, err := d.ParseTime(vals[0], "unix,dup=last")
+			if err != nil {
+				return d.Error("Options.Seen", "X-Seen", raw, err)
+			}
+			s.Options.Seen = t36
+		}
+	}
+	// Options.Extra
+	{
+		if rest37 := d.Rest([]string{"Authorization", "X-Limit", "X-Limit-Max", "X-Limit-Min", "X-Page", "Name", "X-Alias", "X-Flag", "X-Int-Flag", "X-Count", "X-Small", "X-Port", "X-Size", "X-Ratio", "X-Weight", "X-Level", "X-Mode", "X-Color", "X-Token", "X-Retries", "Modified", "X-Expires", "X-Created", "X-Day", "X-Timeout", "X-Delay", "X-Backoff", "X-Request-Id", "X-Forwarded-Proto", "Via", "X-Hops", "X-Part", "X-Seen", "x-lower", "X-Region"}, []string{"X-Meta-", "X-Tag-", "x-cos-meta-"}); rest37 != nil {
+			s.Options.Extra = rest37
+		}
+	}
+	// Options.Meta
+	{
+		d.Prefixed("X-Meta-", func(k38 string, vs39 []string) {
+			if s.Options.Meta == nil {
+				s.Options.Meta = make(map[string]string)
+			}
+			s.Options.Meta[k38] = vs39[0]
+		})
+	}
+	// Options.Tags
+	{
+		d.Prefixed("X-Tag-", func(k40 string, vs41 []string) {
+			if s.Options.Tags == nil {
+				s.Options.Tags = make(map[string][]string)
+			}
+			v42 := make([]string, len(vs41))
+			for i43, s44 := range vs41 {
+				v42[i43] = s44
+			}
+			s.Options.Tags[k40] = v42
+		})
+	}
+	// Options.Object
+	{
+		d.Prefixed("x-cos-meta-", func(k45 string, vs46 []string) {
+			if s.Options.Object == nil {
+				s.Options.Object = make(map[string]string)
+			}
+			s.Options.Object[k45] = vs46[0]
+		})
+	}
+	// Options.Lower
+	{
+		vals := header["x-lower"]
+		if len(vals) > 0 && vals[0] != "" {
+			s.Options.Lower = vals[0]
+		}
+	}
+	// Options.Inner.Region
+	{
+		vals := header["X-Region"]
+		if len(vals) == 0 {
+			vals = []string{"eu"}
+		}
+		if len(vals) > 0 {
+			s.Options.Inner.Region = vals[0]
+		}
+	}
+	return d.Err()
+}
+
+// HTTPHeaderGenerated marks the methods of Nested as generated by
+// httpheader-gen, so that httpheader.Header and httpheader.Decode call them.
+func (Nested) HTTPHeaderGenerated() interface{} {
+	return (*Nested)(nil)
+}
+
+// EncodeHeader implements the httpheader.Encoder interface. It encodes
+// the fields of s into header as described in the documentation for
+// httpheader.Header. key is ignored.
+func (s Lists) EncodeHeader(key string, header *http.Header) error {
+	if *header == nil {
+		*header = make(http.Header)
+	}
+	e := httpheader.GeneratedEncoder{Header: *header, Struct: "Lists"}
+	// Values
+	{
+		for _, v1 := range s.Values {
+			if err := e.Add("Values", "X-Value", v1); err != nil {
+				return err
+			}
+		}
+	}
+	// Comma
+	{
+		if len(s.Comma) > 0 {
+			vs3 := make([]string, 0, len(s.Comma))
+			for _, v2 := range s.Comma {
+				vs3 = append(vs3, v2)
+			}
+			if err := e.AddList("Comma", "X-Comma", vs3, ","); err != nil {
+				return err
+			}
+		}
+	}
+	// Sep
+	{
+		if len(s.Sep) > 0 {
+			vs5 := make([]string, 0, len(s.Sep))
+			for _, v4 := range s.Sep {
+				vs5 = append(vs5, strconv.FormatInt(int64(v4), 10))
+			}
+			if err := e.AddList("Sep", "X-Sep", vs5, ";"); err != nil {
+				return err
+			}
+		}
+	}
+	// Numbers
+	{
+		for _, v6 := range s.Numbers {
+			if err := e.Add("Numbers", "X-Number", strconv.FormatInt(int64(v6), 10)); err != nil {
+				return err
+			}
+		}
+	}
+	// Defaults
+	{
+		if len(s.Defaults) > 0 {
+			vs8 := make([]string, 0, len(s.Defaults))
+			for _, v7 := range s.Defaults {
+				vs8 = append(vs8, v7)
+			}
+			if err := e.AddList("Defaults", "X-Default", vs8, "|"); err != nil {
+				return err
+			}
+		}
+	}
+	// Levels
+	{
+		if len(s.Levels) > 0 {
+			vs10 := make([]string, 0, len(s.Levels))
+			for _, v9 := range s.Levels {
+				vs10 = append(vs10, strconv.FormatInt(int64(v9), 10))
+			}
+			if err := e.AddList("Levels", "X-Levels", vs10, ","); err != nil {
+				return err
+			}
+		}
+	}
+	// Times
+	{
+		for _, v11 := range s.Times {
+			if err := e.Add("Times", "X-Time", e.FormatTime(v11, "unix")); err != nil {
+				return err
+			}
+		}
+	}
+	// Delays
+	{
+		if len(s.Delays) > 0 {
+			vs13 := make([]string, 0, len(s.Delays))
+			for _, v12 := range s.Delays {
+				vs13 = append(vs13, e.FormatDuration(v12, "millis,comma"))
+			}
+			if err := e.AddList("Delays", "X-Delays", vs13, ","); err != nil {
+				return err
+			}
+		}
+	}
+	// Colors
+	{
+		b14, err := s.Colors.MarshalText()
+		if err != nil {
+			return err
+		}
+		if err := e.Add("Colors", "X-Colors", string(b14)); err != nil {
+			return err
+		}
+	}
+	// Bytes
+	{
+		for _, v15 := range s.Bytes {
+			if err := e.Add("Bytes", "X-Bytes", strconv.FormatUint(uint64(v15), 10)); err != nil {
+				return err
+			}
+		}
+	}
+	// Flags
+	{
+		for _, v16 := range s.Flags {
+			v17 := "0"
+			if v16 {
+				v17 = "1"
+			}
+			if err := e.Add("Flags", "X-Flags", v17); err != nil {
+				return err
+			}
+		}
+	}
+	// Page
+	{
+		if err := e.Encode("Page", "X-List-Page", s.Page); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DecodeHeader implements the httpheader.Decoder interface. It decodes
+// header into the fields of s as described in the documentation for
+// httpheader.Decode. key is ignored.
+func (s *Lists) DecodeHeader(header http.Header, key string) error {
+	d := httpheader.GeneratedDecoder{Header: header, Struct: "Lists"}
+	// Values
+	{
+		vals := header["X-Value"]
+		if len(vals) > 0 {
+			sl18 := make([]string, len(vals))
+			for i19, v20 := range vals {
+				sl18[i19] = v20
+			}
+			s.Values = sl18
+		}
+	}
+	// Comma
+	{
+		vals := header["X-Comma"]
+		vals = d.SplitList(vals, ",")
+		if len(vals) > 0 {
+			sl21 := make([]string, len(vals))
+			for i22, v23 := range vals {
+				sl21[i22] = v23
+			}
+			s.Comma = sl21
+		}
+	}
+	// Sep
+	{
+		vals := header["X-Sep"]
+		raw := vals
+		vals = d.SplitList(vals, ";")
+		if len(vals) > 0 && vals[0] != "" {
+			sl24 := make([]int, len(vals))
+			for i25, v26 := range vals {
+				n27, err := strconv.ParseInt(v26, 10, 64)
+				if err != nil {
+					return d.Error("Sep", "X-Sep", raw, err)
+				}
+				sl24[i25] = int(n27)
+			}
+			s.Sep = sl24
+		}
+	}
+	// Numbers
+	{
+		vals := header["X-Number"]
+		if len(vals) == 0 {
+			vals = []string{"1", "2"}
+		}
+		if len(vals) > 0 {
+			sl28 := make([]int, len(vals))
+			for i29, v30 := range vals {
+				n31, err := strconv.ParseInt(v30, 10, 64)
+				if err != nil {
+					return d.Error("Numbers", "X-Number", vals, err)
+				}
+				sl28[i29] = int(n31)
+			}
+			s.Numbers = sl28
+		}
+	}
+	// Defaults
+	{
+		vals := header["X-Default"]
+		fromHeader := len(vals) > 0
+		if len(vals) == 0 {
+			vals = []string{"a", "b"}
+		}
+		if fromHeader {
+			vals = d.SplitList(vals, "|")
+		}
+		if len(vals) > 0 {
+			sl32 := make([]string, len(vals))
+			for i33, v34 := range vals {
+				sl32[i33] = v34
+			}
+			s.Defaults = sl32
+		}
+	}
+	// Levels
+	{
+		vals := header["X-Levels"]
+		raw := vals
+		vals = d.SplitList(vals, ",")
+		if len(vals) > 0 {
+			sl35 := make([]Level, len(vals))
+			for i36, v37 := range vals {
+				n38, err := strconv.ParseInt(v37, 10, 64)
+				if err != nil {
+					return d.Error("Levels", "X-Levels", raw, err)
+				}
+				sl35[i36] = Level(n38)
+			}
+			s.Levels = sl35
+		}
+	}
+	// Times
+	{
+		vals := header["X-Time"]
+		if len(vals) > 0 {
+			sl39 := make([]time.Time, len(vals))
+			for i40, v41 := range vals {
+				t42, err := d.ParseTime(v41, "unix")
+				if err != nil {
+					return d.Error("Times", "X-Time", vals, err)
+				}
+				sl39[i40] = t42
+			}
+			s.Times = sl39
+		}
+	}
+	// Delays
+	{
+		vals := header["X-Delays"]
+		raw := vals
+		vals = d.SplitList(vals, ",")
+		if len(vals) > 0 {
+			sl43 := make([]time.Duration, len(vals))
+			for i44, v45 := range vals {
+				n46, err := d.ParseDuration(v45, "millis,comma")
+				if err != nil {
+					return d.Error("Delays", "X-Delays", raw, err)
+				}
+				sl43[i44] = n46
+			}
+			s.Delays = sl43
+		}
+	}
+	// Colors
+	{
+		vals := header["X-Colors"]
+		if len(vals) > 0 {
+			if err := s.Colors.UnmarshalText([]byte(vals[0])); err != nil {
+				return d.Error("Colors", "X-Colors", vals[:1], err)
+			}
+		}
+	}
+	// Bytes
+	{
+		vals := header["X-Bytes"]
+		if len(vals) > 0 {
+			sl47 := make([]byte, len(vals))
+			for i48, v49 := range vals {
+				n50, err := strconv.ParseUint(v49, 10, 8)
+				if err != nil {
+					return d.Error("Bytes", "X-Bytes", vals, err)
+				}
+				sl47[i48] = byte(n50)
+			}
+			s.Bytes = sl47
+		}
+	}
+	// Flags
+	{
+		vals := header["X-Flags"]
+		if len(vals) > 0 {
+			sl51 := make([]bool, len(vals))
+			for i52, v53 := range vals {
+				sl51[i52] = v53 != "0"
+			}
+			s.Flags = sl51
+		}
+	}
+	// Page
+	if !d.Present("X-List-Page", false) {
+		d.Missing("Page", "X-List-Page")
+	} else {
+		if err := s.Page.DecodeHeader(header, "X-List-Page"); err != nil {
+			return d.Error("Page", "X-List-Page", header["X-List-Page"], err)
+		}
+	}
+	return d.Err()
+}
+
+// HTTPHeaderGenerated marks the methods of Lists as generated by
+// httpheader-gen, so that httpheader.Header and httpheader.Decode call them.
+func (Lists) HTTPHeaderGenerated() interface{} {
+	return (*Lists)(nil)
+}
+
+// EncodeHeader implements the httpheader.Encoder interface. It encodes
+// the fields of s into header as described in the documentation for
+// httpheader.Header. key is ignored.
+func (s Fields) EncodeHeader(key string, header *http.Header) error {
+	if *header == nil {
+		*header = make(http.Header)
+	}
+	e := httpheader.GeneratedEncoder{Header: *header, Struct: "Fields"}
+	// Lists.Values
+	{
+		for _, v1 := range s.Lists.Values {
+			if err := e.Add("Lists.Values", "X-Value", v1); err != nil {
+				return err
+			}
+		}
+	}
+	// Lists.Comma
+	{
+		if len(s.Lists.Comma) > 0 {
+			vs3 := make([]string, 0, len(s.Lists.Comma))
+			for _, v2 := range s.Lists.Comma {
+				vs3 = append(vs3, v2)
+			}
+			if err := e.AddList("Lists.Comma", "X-Comma", vs3, ","); err != nil {
+				return err
+			}
+		}
+	}
+	// Lists.Sep
+	{
+		if len(s.Lists.Sep) > 0 {
+			vs5 := make([]string, 0, len(s.Lists.Sep))
+			for _, v4 := range s.Lists.Sep {
+				vs5 = append(vs5, strconv.FormatInt(int64(v4), 10))
+			}
+			if err := e.AddList("Lists.Sep", "X-Sep", vs5, ";"); err != nil {
+				return err
+			}
+		}
+	}
+	// Lists.Numbers
+	{
+		for _, v6 := range s.Lists.Numbers {
+			if err := e.Add("Lists.Numbers", "X-Number", strconv.FormatInt(int64(v6), 10)); err != nil {
+				return err
+			}
+		}
+	}
+	// Lists.Defaults
+	{
+		if len(s.Lists.Defaults) > 0 {
+			vs8 := make([]string, 0, len(s.Lists.Defaults))
+			for _, v7 := range s.Lists.Defaults {
+				vs8 = append(vs8, v7)
+			}
+			if err := e.AddList("Lists.Defaults", "X-Default", vs8, "|"); err != nil {
+				return err
+			}
+		}
+	}
+	// Lists.Levels
+	{
+		if len(s.Lists.Levels) > 0 {
+			vs10 := make([]string, 0, len(s.Lists.Levels))
+			for _, v9 := range s.Lists.Levels {
+				vs10 = append(vs10, strconv.FormatInt(int64(v9), 10))
+			}
+			if err := e.AddList("Lists.Levels", "X-Levels", vs10, ","); err != nil {
+				return err
+			}
+		}
+	}
+	// Lists.Times
+	{
+		for _, v11 := range s.Lists.Times {
+			if err := e.Add("Lists.Times", "X-Time", e.FormatTime(v11, "unix")); err != nil {
+				return err
+			}
+		}
+	}
+	// Lists.Delays
+	{
+		if len(s.Lists.Delays) > 0 {
+			vs13 := make([]string, 0, len(s.Lists.Delays))
+			for _, v12 := range s.Lists.Delays {
+				vs13 = append(vs13, e.FormatDuration(v12, "millis,comma"))
+			}
+			if err := e.AddList("Lists.Delays", "X-Delays", vs13, ","); err != nil {
+				return err
+			}
+		}
+	}
+	// Lists.Colors
+	{
+		b14, err := s.Lists.Colors.MarshalText()
+		if err != nil {
+			return err
+		}
+		if err := e.Add("Lists.Colors", "X-Colors", string(b14)); err != nil {
+			return err
+		}
+	}
+	// Lists.Bytes
+	{
+		for _, v15 := range s.Lists.Bytes {
+			if err := e.Add("Lists.Bytes", "X-Bytes", strconv.FormatUint(uint64(v15), 10)); err != nil {
+				return err
+			}
+		}
+	}
+	// Lists.Flags
+	{
+		for _, v16 := range s.Lists.Flags {
+			v17 := "0"
+			if v16 {
+				v17 = "1"
+			}
+			if err := e.Add("Lists.Flags", "X-Flags", v17); err != nil {
+				return err
+			}
+		}
+	}
+	// Lists.Page
+	{
+		if err := e.Encode("Lists.Page", "X-List-Page", s.Lists.Page); err != nil {
+			return err
+		}
+	}
+	// Wrapper.B
+	{
+		if err := e.Add("Wrapper.B", "X-B", s.Wrapper.B); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Name
+	{
+		if err := e.Add("Wrapper.Options.Name", "Name", s.Wrapper.Options.Name); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Alias
+	if len(s.Wrapper.Options.Alias) > 0 {
+		if err := e.Add("Wrapper.Options.Alias", "X-Alias", s.Wrapper.Options.Alias); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Flag
+	{
+		if err := e.Add("Wrapper.Options.Flag", "X-Flag", strconv.FormatBool(s.Wrapper.Options.Flag)); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.IntFlag
+	{
+		v18 := "0"
+		if s.Wrapper.Options.IntFlag {
+			v18 = "1"
+		}
+		if err := e.Add("Wrapper.Options.IntFlag", "X-Int-Flag", v18); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Count
+	if s.Wrapper.Options.Count != 0 {
+		if err := e.Add("Wrapper.Options.Count", "X-Count", strconv.FormatInt(int64(s.Wrapper.Options.Count), 10)); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Small
+	{
+		if err := e.Add("Wrapper.Options.Small", "X-Small", strconv.FormatInt(int64(s.Wrapper.Options.Small), 10)); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Port
+	{
+		if err := e.Add("Wrapper.Options.Port", "X-Port", strconv.FormatUint(uint64(s.Wrapper.Options.Port), 10)); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Size
+	{
+		if err := e.Add("Wrapper.Options.Size", "X-Size", strconv.FormatUint(s.Wrapper.Options.Size, 10)); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Ratio
+	{
+		if err := e.Add("Wrapper.Options.Ratio", "X-Ratio", strconv.FormatFloat(s.Wrapper.Options.Ratio, 'g', -1, 64)); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Weight
+	if s.Wrapper.Options.Weight != 0 {
+		if err := e.Add("Wrapper.Options.Weight", "X-Weight", strconv.FormatFloat(float64(s.Wrapper.Options.Weight), 'g', -1, 32)); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Level
+	{
+		if err := e.Add("Wrapper.Options.Level", "X-Level", strconv.FormatInt(int64(s.Wrapper.Options.Level), 10)); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Mode
+	{
+		if err := e.Add("Wrapper.Options.Mode", "X-Mode", fmt.Sprint(s.Wrapper.Options.Mode)); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Color
+	{
+		b19, err := s.Wrapper.Options.Color.MarshalText()
+		if err != nil {
+			return err
+		}
+		if err := e.Add("Wrapper.Options.Color", "X-Color", string(b19)); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Token
+	{
+		v20 := ""
+		if s.Wrapper.Options.Token != nil {
+			v20 = (*s.Wrapper.Options.Token)
+		}
+		if err := e.Add("Wrapper.Options.Token", "X-Token", v20); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Retries
+	if s.Wrapper.Options.Retries != nil {
+		v21 := ""
+		if s.Wrapper.Options.Retries != nil {
+			v21 = strconv.FormatInt(int64((*s.Wrapper.Options.Retries)), 10)
+		}
+		if err := e.Add("Wrapper.Options.Retries", "X-Retries", v21); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Modified
+	{
+		if err := e.Add("Wrapper.Options.Modified", "Modified", e.FormatTime(s.Wrapper.Options.Modified, "")); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Expires
+	if !s.Wrapper.Options.Expires.IsZero() {
+		if err := e.Add("Wrapper.Options.Expires", "X-Expires", e.FormatTime(s.Wrapper.Options.Expires, "unix,omitempty")); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Created
+	{
+		if err := e.Add("Wrapper.Options.Created", "X-Created", e.FormatTime(s.Wrapper.Options.Created, "rfc3339")); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Day
+	{
+		if err := e.Add("Wrapper.Options.Day", "X-Day", e.FormatTime(s.Wrapper.Options.Day, "layout=2006-01-02")); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Timeout
+	{
+		if err := e.Add("Wrapper.Options.Timeout", "X-Timeout", e.FormatDuration(s.Wrapper.Options.Timeout, "")); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Delay
+	if s.Wrapper.Options.Delay != 0 {
+		if err := e.Add("Wrapper.Options.Delay", "X-Delay", e.FormatDuration(s.Wrapper.Options.Delay, "seconds,omitempty")); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Backoff
+	{
+		if err := e.Add("Wrapper.Options.Backoff", "X-Backoff", strconv.FormatInt(int64(s.Wrapper.Options.Backoff), 10)); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.RequestID
+	{
+		if err := e.Add("Wrapper.Options.RequestID", "X-Request-Id", s.Wrapper.Options.RequestID); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Proto
+	{
+		if err := e.Add("Wrapper.Options.Proto", "X-Forwarded-Proto", s.Wrapper.Options.Proto); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Via
+	{
+		if err := e.Add("Wrapper.Options.Via", "Via", s.Wrapper.Options.Via); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Hops
+	{
+		v22 := ""
+		if s.Wrapper.Options.Hops != nil {
+			v22 = strconv.FormatInt(int64((*s.Wrapper.Options.Hops)), 10)
+		}
+		if err := e.Add("Wrapper.Options.Hops", "X-Hops", v22); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Part
+	{
+		v23 := ""
+		if s.Wrapper.Options.Part != nil {
+			v23 = (*s.Wrapper.Options.Part)
+		}
+		if err := e.Add("Wrapper.Options.Part", "X-Part", v23); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Seen
+	{
+		if err := e.Add("Wrapper.Options.Seen", "X-Seen", e.FormatTime(s.Wrapper.Options.Seen, "unix,dup=last")); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Extra
+	{
+		if err := e.AddHeader("Wrapper.Options.Extra", s.Wrapper.Options.Extra, true); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Meta
+	{
+		for k24, v25 := range s.Wrapper.Options.Meta {
+			if err := e.Add("Wrapper.Options.Meta", http.CanonicalHeaderKey("X-Meta-"+k24), v25); err != nil {
+				return err
+			}
+		}
+	}
+	// Wrapper.Options.Tags
+	{
+		for k26, v27 := range s.Wrapper.Options.Tags {
+			for _, s28 := range v27 {
+				if err := e.Add("Wrapper.Options.Tags", http.CanonicalHeaderKey("X-Tag-"+k26), s28); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	// Wrapper.Options.Object
+	{
+		for k29, v30 := range s.Wrapper.Options.Object {
+			if err := e.Add("Wrapper.Options.Object", "x-cos-meta-"+k29, v30); err != nil {
+				return err
+			}
+		}
+	}
+	// Wrapper.Options.Lower
+	if len(s.Wrapper.Options.Lower) > 0 {
+		if err := e.Add("Wrapper.Options.Lower", "x-lower", s.Wrapper.Options.Lower); err != nil {
+			return err
+		}
+	}
+	// Wrapper.Options.Inner.Region
+	{
+		if err := e.Add("Wrapper.Options.Inner.Region", "X-Region", s.Wrapper.Options.Inner.Region); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DecodeHeader implements the httpheader.Decoder interface. It decodes
+// header into the fields of s as described in the documentation for
+// httpheader.Decode. key is ignored.
+func (s *Fields) DecodeHeader(header http.Header, key string) error {
+	d := httpheader.GeneratedDecoder{Header: header, Struct: "Fields"}
+	// Lists.Values
+	{
+		vals := header["X-Value"]
+		if len(vals) > 0 {
+			sl31 := make([]string, len(vals))
+			for i32, v33 := range vals {
+				sl31[i32] = v33
+			}
+			s.Lists.Values = sl31
+		}
+	}
+	// Lists.Comma
+	{
+		vals := header["X-Comma"]
+		vals = d.SplitList(vals, ",")
+		if len(vals) > 0 {
+			sl34 := make([]string, len(vals))
+			for i35, v36 := range vals {
+				sl34[i35] = v36
+			}
+			s.Lists.Comma = sl34
+		}
+	}
+	// Lists.Sep
+	{
+		vals := header["X-Sep"]
+		raw := vals
+		vals = d.SplitList(vals, ";")
+		if len(vals) > 0 && vals[0] != "" {
+			sl37 := make([]int, len(vals))
+			for i38, v39 := range vals {
+				n40, err := strconv.ParseInt(v39, 10, 64)
+				if err != nil {
+					return d.Error("Lists.Sep", "X-Sep", raw, err)
+				}
+				sl37[i38] = int(n40)
+			}
+			s.Lists.Sep = sl37
+		}
+	}
+	// Lists.Numbers
+	{
+		vals := header["X-Number"]
+		if len(vals) == 0 {
+			vals = []string{"1", "2"}
+		}
+		if len(vals) > 0 {
+			sl41 := make([]int, len(vals))
+			for i42, v43 := range vals {
+				n44, err := strconv.ParseInt(v43, 10, 64)
+				if err != nil {
+					return d.Error("Lists.Numbers", "X-Number", vals, err)
+				}
+				sl41[i42] = int(n44)
+			}
+			s.Lists.Numbers = sl41
+		}
+	}
+	// Lists.Defaults
+	{
+		vals := header["X-Default"]
+		fromHeader := len(vals) > 0
+		if len(vals) == 0 {
+			vals = []string{"a", "b"}
+		}
+		if fromHeader {
+			vals = d.SplitList(vals, "|")
+		}
+		if len(vals) > 0 {
+			sl45 := make([]string, len(vals))
+			for i46, v47 := range vals {
+				sl45[i46] = v47
+			}
+			s.Lists.Defaults = sl45
+		}
+	}
+	// Lists.Levels
+	{
+		vals := header["X-Levels"]
+		raw := vals
+		vals = d.SplitList(vals, ",")
+		if len(vals) > 0 {
+			sl48 := make([]Level, len(vals))
+			for i49, v50 := range vals {
+				n51, err := strconv.ParseInt(v50, 10, 64)
+				if err != nil {
+					return d.Error("Lists.Levels", "X-Levels", raw, err)
+				}
+				sl48[i49] = Level(n51)
+			}
+			s.Lists.Levels = sl48
+		}
+	}
+	// Lists.Times
+	{
+		vals := header["X-Time"]
+		if len(vals) > 0 {
+			sl52 := make([]time.Time, len(vals))
+			for i53, v54 := range vals {
+				t55, err := d.ParseTime(v54, "unix")
+				if err != nil {
+					return d.Error("Lists.Times", "X-Time", vals, err)
+				}
+				sl52[i53] = t55
+			}
+			s.Lists.Times = sl52
+		}
+	}
+	// Lists.Delays
+	{
+		vals := header["X-Delays"]
+		raw := vals
+		vals = d.SplitList(vals, ",")
+		if len(vals) > 0 {
+			sl56 := make([]time.Duration, len(vals))
+			for i57, v58 := range vals {
+				n59, err := d.ParseDuration(v58, "millis,comma")
+				if err != nil {
+					return d.Error("Lists.Delays", "X-Delays", raw, err)
+				}
+				sl56[i57] = n59
+			}
+			s.Lists.Delays = sl56
+		}
+	}
+	// Lists.Colors
+	{
+		vals := header["X-Colors"]
+		if len(vals) > 0 {
+			if err := s.Lists.Colors.UnmarshalText([]byte(vals[0])); err != nil {
+				return d.Error("Lists.Colors", "X-Colors", vals[:1], err)
+			}
+		}
+	}
+	// Lists.Bytes
+	{
+		vals := header["X-Bytes"]
+		if len(vals) > 0 {
+			sl60 := make([]byte, len(vals))
+			for i61, v62 := range vals {
+				n63, err := strconv.ParseUint(v62, 10, 8)
+				if err != nil {
+					return d.Error("Lists.Bytes", "X-Bytes", vals, err)
+				}
+				sl60[i61] = byte(n63)
+			}
+			s.Lists.Bytes = sl60
+		}
+	}
+	// Lists.Flags
+	{
+		vals := header["X-Flags"]
+		if len(vals) > 0 {
+			sl64 := make([]bool, len(vals))
+			for i65, v66 := range vals {
+				sl64[i65] = v66 != "0"
+			}
+			s.Lists.Flags = sl64
+		}
+	}
+	// Lists.Page
+	if !d.Present("X-List-Page", false) {
+		d.Missing("Lists.Page", "X-List-Page")
+	} else {
+		if err := s.Lists.Page.DecodeHeader(header, "X-List-Page"); err != nil {
+			return d.Error("Lists.Page", "X-List-Page", header["X-List-Page"], err)
+		}
+	}
+	// Wrapper.B
+	{
+		vals := header["X-B"]
+		if len(vals) > 0 {
+			s.Wrapper.B = vals[0]
+		}
+	}
+	// Wrapper.Options.Name
+	{
+		vals := header["Name"]
+		if len(vals) > 0 {
+			s.Wrapper.Options.Name = vals[0]
+		}
+	}
+	// Wrapper.Options.Alias
+	{
+		vals := header["X-Alias"]
+		if len(vals) > 0 && vals[0] != "" {
+			s.Wrapper.Options.Alias = vals[0]
+		}
+	}
+	// Wrapper.Options.Flag
+	{
+		vals := header["X-Flag"]
+		if len(vals) > 0 {
+			s.Wrapper.Options.Flag = vals[0] != "false"
+		}
+	}
+	// Wrapper.Options.IntFlag
+	{
+		vals := header["X-Int-Flag"]
+		if len(vals) > 0 {
+			s.Wrapper.Options.IntFlag = vals[0] != "0"
+		}
+	}
+	// Wrapper.Options.Count
+	{
+		vals := header["X-Count"]
+		if len(vals) > 0 && vals[0] != "" {
+			n67, err := strconv.ParseInt(vals[0], 10, 64)
+			if err != nil {
+				return d.Error("Wrapper.Options.Count", "X-Count", vals[:1], err)
+			}
+			s.Wrapper.Options.Count = int(n67)
+		}
+	}
+	// Wrapper.Options.Small
+	{
+		vals := header["X-Small"]
+		if len(vals) > 0 {
+			n68, err := strconv.ParseInt(vals[0], 10, 8)
+			if err != nil {
+				return d.Error("Wrapper.Options.Small", "X-Small", vals[:1], err)
+			}
+			s.Wrapper.Options.Small = int8(n68)
+		}
+	}
+	// Wrapper.Options.Port
+	{
+		vals := header["X-Port"]
+		if len(vals) > 0 {
+			n69, err := strconv.ParseUint(vals[0], 10, 16)
+			if err != nil {
+				return d.Error("Wrapper.Options.Port", "X-Port", vals[:1], err)
+			}
+			s.Wrapper.Options.Port = uint16(n69)
+		}
+	}
+	// Wrapper.Options.Size
+	{
+		vals := header["X-Size"]
+		if len(vals) == 0 {
+			vals = []string{"512"}
+		}
+		if len(vals) > 0 {
+			n70, err := strconv.ParseUint(vals[0], 10, 64)
+			if err != nil {
+				return d.Error("Wrapper.Options.Size", "X-Size", vals[:1], err)
+			}
+			s.Wrapper.Options.Size = n70
+		}
+	}
+	// Wrapper.Options.Ratio
+	{
+		vals := header["X-Ratio"]
+		if len(vals) > 0 {
+			n71, err := strconv.ParseFloat(vals[0], 64)
+			if err != nil {
+				return d.Error("Wrapper.Options.Ratio", "X-Ratio", vals[:1], err)
+			}
+			s.Wrapper.Options.Ratio = n71
+		}
+	}
+	// Wrapper.Options.Weight
+	{
+		vals := header["X-Weight"]
+		if len(vals) > 0 && vals[0] != "" {
+			n72, err := strconv.ParseFloat(vals[0], 32)
+			if err != nil {
+				return d.Error("Wrapper.Options.Weight", "X-Weight", vals[:1], err)
+			}
+			s.Wrapper.Options.Weight = float32(n72)
+		}
+	}
+	// Wrapper.Options.Level
+	{
+		vals := header["X-Level"]
+		if len(vals) > 0 {
+			n73, err := strconv.ParseInt(vals[0], 10, 64)
+			if err != nil {
+				return d.Error("Wrapper.Options.Level", "X-Level", vals[:1], err)
+			}
+			s.Wrapper.Options.Level = Level(n73)
+		}
+	}
+	// Wrapper.Options.Mode
+	{
+		vals := header["X-Mode"]
+		if len(vals) > 0 {
+			s.Wrapper.Options.Mode = Mode(vals[0])
+		}
+	}
+	// Wrapper.Options.Color
+	{
+		vals := header["X-Color"]
+		if len(vals) > 0 && vals[0] != "" {
+			if err := s.Wrapper.Options.Color.UnmarshalText([]byte(vals[0])); err != nil {
+				return d.Error("Wrapper.Options.Color", "X-Color", vals[:1], err)
+			}
+		}
+	}
+	// Wrapper.Options.Token
+	{
+		vals := header["X-Token"]
+		if len(vals) > 0 {
+			p74 := new(string)
+			(*p74) = vals[0]
+			s.Wrapper.Options.Token = p74
+		}
+	}
+	// Wrapper.Options.Retries
+	{
+		vals := header["X-Retries"]
+		if len(vals) > 0 && vals[0] != "" {
+			p75 := new(int)
+			n76, err := strconv.ParseInt(vals[0], 10, 64)
+			if err != nil {
+				return d.Error("Wrapper.Options.Retries", "X-Retries", vals, err)
+			}
+			(*p75) = int(n76)
+			s.Wrapper.Options.Retries = p75
+		}
+	}
+	// Wrapper.Options.Modified
+	{
+		vals := header["Modified"]
+		if len(vals) > 0 {
+			t77, err := d.ParseTime(vals[0], "")
+			if err != nil {
+				return d.Error("Wrapper.Options.Modified", "Modified", vals, err)
+			}
+			s.Wrapper.Options.Modified = t77
+		}
+	}
+	// Wrapper.Options.Expires
+	{
+		vals := header["X-Expires"]
+		if len(vals) > 0 && vals[0] != "" {
+			t78, err := d.ParseTime(vals[0], "unix,omitempty")
+			if err != nil {
+				return d.Error("Wrapper.Options.Expires", "X-Expires", vals, err)
+			}
+			s.Wrapper.Options.Expires = t78
+		}
+	}
+	// Wrapper.Options.Created
+	{
+		vals := header["X-Created"]
+		if len(vals) > 0 {
+			t79, err := d.ParseTime(vals[0], "rfc3339")
+			if err != nil {
+				return d.Error("Wrapper.Options.Created", "X-Created", vals, err)
+			}
+			s.Wrapper.Options.Created = t79
+		}
+	}
+	// Wrapper.Options.Day
+	{
+		vals := header["X-Day"]
+		if len(vals) > 0 {
+			t80, err := d.ParseTime(vals[0], "layout=2006-01-02")
+			if err != nil {
+				return d.Error("Wrapper.Options.Day", "X-Day", vals, err)
+			}
+			s.Wrapper.Options.Day = t80
+		}
+	}
+	// Wrapper.Options.Timeout
+	{
+		vals := header["X-Timeout"]
+		if len(vals) > 0 {
+			n81, err := d.ParseDuration(vals[0], "")
+			if err != nil {
+				return d.Error("Wrapper.Options.Timeout", "X-Timeout", vals[:1], err)
+			}
+			s.Wrapper.Options.Timeout = n81
+		}
+	}
+	// Wrapper.Options.Delay
+	{
+		vals := header["X-Delay"]
+		if len(vals) > 0 && vals[0] != "" {
+			n82, err := d.ParseDuration(vals[0], "seconds,omitempty")
+			if err != nil {
+				return d.Error("Wrapper.Options.Delay", "X-Delay", vals[:1], err)
+			}
+			s.Wrapper.Options.Delay = n82
+		}
+	}
+	// Wrapper.Options.Backoff
+	{
+		vals := header["X-Backoff"]
+		if len(vals) > 0 {
+			n83, err := strconv.ParseInt(vals[0], 10, 64)
+			if err != nil {
+				return d.Error("Wrapper.Options.Backoff", "X-Backoff", vals[:1], err)
+			}
+			s.Wrapper.Options.Backoff = Backoff(n83)
+		}
+	}
+	// Wrapper.Options.RequestID
+	if !d.Present("X-Request-Id", false) {
+		d.Missing("Wrapper.Options.RequestID", "X-Request-Id")
+	} else {
+		vals := header["X-Request-Id"]
+		if len(vals) > 0 {
+			s.Wrapper.Options.RequestID = vals[0]
+		}
+	}
+	// Wrapper.Options.Proto
+	{
+		vals := header["X-Forwarded-Proto"]
+		vals, err := d.Duplicates("Wrapper.Options.Proto", "X-Forwarded-Proto", vals, "dup=unique")
+		if err != nil {
+			return err
+		}
+		if len(vals) > 0 {
+			s.Wrapper.Options.Proto = vals[0]
+		}
+	}
+	// Wrapper.Options.Via
+	{
+		vals := header["Via"]
+		vals, err := d.Duplicates("Wrapper.Options.Via", "Via", vals, "dup=join,sep=;")
+		if err != nil {
+			return err
+		}
+		if len(vals) > 0 {
+			s.Wrapper.Options.Via = vals[0]
+		}
+	}
+	// Wrapper.Options.Hops
+	{
+		vals := header["X-Hops"]
+		raw := vals
+		vals, err := d.Duplicates("Wrapper.Options.Hops", "X-Hops", vals, "dup=last")
+		if err != nil {
+			return err
+		}
+		if len(vals) > 0 {
+			p84 := new(int)
+			n85, err := strconv.ParseInt(vals[0], 10, 64)
+			if err != nil {
+				return d.Error("Wrapper.Options.Hops", "X-Hops", raw, err)
+			}
+			(*p84) = int(n85)
+			s.Wrapper.Options.Hops = p84
+		}
+	}
+	// Wrapper.Options.Part
+	{
+		vals := header["X-Part"]
+		vals, err := d.Duplicates("Wrapper.Options.Part", "X-Part", vals, "dup=last,sep=;")
+		if err != nil {
+			return err
+		}
+		if len(vals) > 0 {
+			p86 := new(string)
+			(*p86) = vals[0]
+			s.Wrapper.Options.Part = p86
+		}
+	}
+	// Wrapper.Options.Seen
+	{
+		vals := header["X-Seen"]
+		raw := vals
+		vals, err := d.Duplicates("Wrapper.Options.Seen", "X-Seen", vals, "unix,dup=last")
+		if err != nil {
+			return err
+		}
+		if len(vals) > 0 {
+			t87, err := d.ParseTime(vals[0], "unix,dup=last")
+			if err != nil {
+				return d.Error("Wrapper.Options.Seen", "X-Seen", raw, err)
+			}
+			s.Wrapper.Options.Seen = t87
+		}
+	}
+	// Wrapper.Options.Extra
+	{
+		if rest88 := d.Rest([]string{"X-Value", "X-Comma", "X-Sep", "X-Number", "X-Default", "X-Levels", "X-Time", "X-Delays", "X-Colors", "X-Bytes", "X-Flags", "X-List-Page", "X-B", "Name", "X-Alias", "X-Flag", "X-Int-Flag", "X-Count", "X-Small", "X-Port", "X-Size", "X-Ratio", "X-Weight", "X-Level", "X-Mode", "X-Color", "X-Token", "X-Retries", "Modified", "X-Expires", "X-Created", "X-Day", "X-Timeout", "X-Delay", "X-Backoff", "X-Request-Id", "X-Forwarded-Proto", "Via", "X-Hops", "X-Part", "X-Seen", "x-lower", "X-Region"}, []string{"X-Meta-", "X-Tag-", "x-cos-meta-"}); rest88 != nil {
+			s.Wrapper.Options.Extra = rest88
+		}
+	}
+	// Wrapper.Options.Meta
+	{
+		d.Prefixed("X-Meta-", func(k89 string, vs90 []string) {
+			if s.Wrapper.Options.Meta == nil {
+				s.Wrapper.Options.Meta = make(map[string]string)
+			}
+			s.Wrapper.Options.Meta[k89] = vs90[0]
+		})
+	}
+	// Wrapper.Options.Tags
+	{
+		d.Prefixed("X-Tag-", func(k91 string, vs92 []string) {
+			if s.Wrapper.Options.Tags == nil {
+				s.Wrapper.Options.Tags = make(map[string][]string)
+			}
+			v93 := make([]string, len(vs92))
+			for i94, s95 := range vs92 {
+				v93[i94] = s95
+			}
+			s.Wrapper.Options.Tags[k91] = v93
+		})
+	}
+	// Wrapper.Options.Object
+	{
+		d.Prefixed("x-cos-meta-", func(k96 string, vs97 []string) {
+			if s.Wrapper.Options.Object == nil {
+				s.Wrapper.Options.Object = make(map[string]string)
+			}
+			s.Wrapper.Options.Object[k96] = vs97[0]
+		})
+	}
+	// Wrapper.Options.Lower
+	{
+		vals := header["x-lower"]
+		if len(vals) > 0 && vals[0] != "" {
+			s.Wrapper.Options.Lower = vals[0]
+		}
+	}
+	// Wrapper.Options.Inner.Region
+	{
+		vals := header["X-Region"]
+		if len(vals) == 0 {
+			vals = []string{"eu"}
+		}
+		if len(vals) > 0 {
+			s.Wrapper.Options.Inner.Region = vals[0]
+		}
+	}
+	return d.Err()
+}
+
+// HTTPHeaderGenerated marks the methods of Fields as generated by
+// httpheader-gen, so that httpheader.Header and httpheader.Decode call them.
+func (Fields) HTTPHeaderGenerated() interface{} {
+	return (*Fields)(nil)
+}
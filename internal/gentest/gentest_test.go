@@ -0,0 +1,324 @@
+package gentest
+
+import (
+	"math"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mozillazg/go-httpheader"
+)
+
+func stringPtr(s string) *string { return &s }
+func intPtr(i int) *int          { return &i }
+
+var encodeTests = []interface{}{
+	Options{},
+	&Options{
+		Name:      "foo",
+		Alias:     "bar",
+		Flag:      true,
+		IntFlag:   true,
+		Count:     -3,
+		Small:     -128,
+		Port:      8080,
+		Size:      math.MaxUint64,
+		Ratio:     1.5e-7,
+		Weight:    0.1,
+		Level:     7,
+		Mode:      "fast",
+		Color:     Color{0x12, 0xab, 0xff},
+		Token:     stringPtr("t"),
+		Retries:   intPtr(0),
+		Modified:  time.Date(2021, 7, 1, 12, 30, 0, 0, time.FixedZone("X", 3600)),
+		Expires:   time.Unix(1600000000, 0),
+		Created:   time.Date(2021, 7, 1, 12, 30, 0, 5, time.UTC),
+		Day:       time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC),
+		Timeout:   1500 * time.Millisecond,
+		Delay:     2500 * time.Millisecond,
+		Backoff:   Backoff(time.Second),
+		RequestID: "id",
+		Proto:     "https",
+		Via:       "a;b",
+		Hops:      intPtr(2),
+		Extra:     http.Header{"x-extra": {"a", "b"}},
+		Meta:      map[string]string{"one": "1", "TWO": "2"},
+		Tags:      map[string][]string{"a": {"x", "y"}},
+		Object:    map[string]string{"MyKey": "v"},
+		Lower:     "lower",
+		Ignored:   "ignored",
+		internal:  "internal",
+		Inner:     Inner{Region: "us"},
+	},
+	Nested{},
+	Nested{
+		Options: Options{Name: "foo", Ratio: 1e21},
+		Auth:    Auth{User: "user", Password: "pass"},
+		Limits:  Limits{Max: 10, Min: 1},
+		Page:    Page{Size: 3},
+	},
+	&Lists{},
+	Lists{
+		Values:   []string{"a", "b"},
+		Comma:    []string{"a", "b c", `"d, e"`},
+		Sep:      []int{1, 2},
+		Numbers:  []int{3},
+		Defaults: []string{"x", "y"},
+		Levels:   []Level{1, 2},
+		Times:    []time.Time{time.Unix(1, 0), time.Unix(2, 0)},
+		Delays:   []time.Duration{time.Millisecond, 1500 * time.Microsecond},
+		Colors:   Colors{{1, 2, 3}, {4, 5, 6}},
+		Bytes:    []byte("hi"),
+		Flags:    []bool{true, false},
+		Page:     Page{Size: 1},
+	},
+	Fields{
+		Lists:   Lists{Values: []string{"a"}, Page: Page{Size: 2}},
+		Wrapper: Wrapper{Options: Options{Name: "foo", RequestID: "id", Hops: intPtr(1)}, B: "b"},
+	},
+}
+
+func TestGeneratedEncode(t *testing.T) {
+	for _, v := range encodeTests {
+		want, wantErr := httpheader.NewEncoder().Encode(v)
+		got, err := httpheader.Header(v)
+		if !reflect.DeepEqual(err, wantErr) {
+			t.Errorf("Header(%#v) returned error %v, want %v", v, err, wantErr)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Header(%#v) = %v, want %v", v, got, want)
+		}
+	}
+}
+
+func TestGeneratedEncode_errors(t *testing.T) {
+	tests := []interface{}{
+		Options{Name: "a\nb"},
+		Options{Meta: map[string]string{"a b": "c"}},
+		Options{Extra: http.Header{"X": {"\x00"}}},
+		Nested{Page: Page{Size: -1}},
+		Lists{Values: []string{"ok", "\r"}},
+		Lists{Comma: []string{"a, b", `c"d`}},
+	}
+	for _, v := range tests {
+		_, wantErr := httpheader.NewEncoder().Encode(v)
+		_, err := httpheader.Header(v)
+		if wantErr == nil {
+			t.Fatalf("Encode(%#v) returned no error", v)
+		}
+		if !reflect.DeepEqual(err, wantErr) {
+			t.Errorf("Header(%#v) returned error %v, want %v", v, err, wantErr)
+		}
+	}
+}
+
+var decodeTests = []http.Header{
+	{},
+	{"X-Request-Id": {"id"}, "X-List-Page": {""}},
+	{
+		"Name":              {"foo", "bar"},
+		"X-Alias":           {""},
+		"X-Flag":            {"no"},
+		"X-Int-Flag":        {"0"},
+		"X-Count":           {"-3"},
+		"X-Small":           {"12"},
+		"X-Port":            {"8080"},
+		"X-Size":            {"18446744073709551615"},
+		"X-Ratio":           {"1.5e-7"},
+		"X-Weight":          {"0.1"},
+		"X-Level":           {"7"},
+		"X-Mode":            {"fast"},
+		"X-Color":           {"#12abff"},
+		"X-Token":           {""},
+		"X-Retries":         {"3"},
+		"Modified":          {"Thu, 01 Jul 2021 11:30:00 GMT"},
+		"X-Expires":         {"1600000000"},
+		"X-Created":         {"2021-07-01T12:30:00.000000005Z"},
+		"X-Day":             {"2021-07-01"},
+		"X-Timeout":         {"1.5s"},
+		"X-Delay":           {"2.5"},
+		"X-Backoff":         {"1000"},
+		"X-Request-Id":      {"id"},
+		"X-Forwarded-Proto": {"https"},
+		"Via":               {"a", "b"},
+		"X-Hops":            {"1", "2"},
+		"X-Part":            {"a;b", "c;d"},
+		"X-Seen":            {"1", "2"},
+		"X-Meta-One":        {"1", "one"},
+		"X-Tag-A":           {"x", "y"},
+		"X-Region":          {"us"},
+		"Authorization":     {"user:pass"},
+		"X-Limit":           {"yes"},
+		"X-Limit-Max":       {"10"},
+		"X-Limit-Min":       {"1"},
+		"X-Page":            {"***"},
+		"X-Value":           {"a", "b"},
+		"X-Comma":           {`a, "b, c"`, "d"},
+		"X-Sep":             {"1;2", "3"},
+		"X-Number":          {"3"},
+		"X-Default":         {"x|y"},
+		"X-Levels":          {"1, 2"},
+		"X-Time":            {"1", "2"},
+		"X-Delays":          {"1, 1.5"},
+		"X-Colors":          {"#010203 #040506"},
+		"X-Bytes":           {"104", "105"},
+		"X-Flags":           {"1", "0"},
+		"X-List-Page":       {"*"},
+	},
+	{"X-Limit": {""}, "X-Limit-Max": {"10"}, "X-Sep": {""}, "X-Default": {" | "}},
+}
+
+var decodeErrorTests = []http.Header{
+	{"X-Request-Id": {"id"}, "X-Count": {"x"}},
+	{"X-Request-Id": {"id"}, "X-Small": {"1000"}},
+	{"X-Request-Id": {"id"}, "X-Port": {"-1"}},
+	{"X-Request-Id": {"id"}, "X-Ratio": {"r"}},
+	{"X-Request-Id": {"id"}, "X-Color": {"red"}},
+	{"X-Request-Id": {"id"}, "X-Retries": {"1", "x"}},
+	{"X-Request-Id": {"id"}, "X-Retries": {"x", "1"}},
+	{"X-Request-Id": {"id"}, "Modified": {"yesterday"}},
+	{"X-Request-Id": {"id"}, "X-Expires": {"soon"}},
+	{"X-Request-Id": {"id"}, "X-Timeout": {"1x"}},
+	{"X-Request-Id": {"id"}, "X-Delay": {"NaN"}},
+	{"X-Request-Id": {"id"}, "Authorization": {"user"}},
+	{"X-Request-Id": {"id"}, "X-Forwarded-Proto": {"http", "https"}},
+	{"X-Request-Id": {"id"}, "X-Hops": {"x", "1"}, "X-Seen": {"1", "x"}},
+	{"X-Request-Id": {"id"}, "x-lower": {"a"}, "X-Lower": {"b"}},
+	{"X-Request-Id": {"id"}, "X-Limit": {"1"}, "X-Limit-Max": {"max"}},
+	{"X-Request-Id": {"id"}, "X-Page": {"page"}},
+	{"X-List-Page": {"*"}, "X-Sep": {"1;x"}},
+	{"X-List-Page": {"*"}, "X-Number": {"1", "x"}},
+	{"X-List-Page": {"*"}, "X-Time": {"1", "x"}},
+	{"X-List-Page": {"*"}, "X-Delays": {"1, x"}},
+	{"X-List-Page": {"*"}, "X-Bytes": {"256"}},
+	{"X-List-Page": {"*"}, "X-Colors": {"#0"}},
+	{"X-List-Page": {"?"}},
+}
+
+func TestGeneratedDecode(t *testing.T) {
+	for _, h := range append(decodeTests, decodeErrorTests...) {
+		for _, newValue := range []func() interface{}{
+			func() interface{} { return new(Options) },
+			func() interface{} { return new(Nested) },
+			func() interface{} { return new(Lists) },
+			func() interface{} { return new(Fields) },
+		} {
+			want, got := newValue(), newValue()
+			wantErr := httpheader.NewDecoder().Decode(h, want)
+			err := httpheader.Decode(h, got)
+			if !reflect.DeepEqual(err, wantErr) {
+				t.Errorf("Decode(%v, %T) returned error %v, want %v", h, got, err, wantErr)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Decode(%v, %T) = %+v, want %+v", h, got, got, want)
+			}
+		}
+	}
+}
+
+func TestGeneratedDecode_errors(t *testing.T) {
+	for _, h := range decodeErrorTests {
+		var errs int
+		for _, v := range []interface{}{new(Options), new(Nested), new(Lists)} {
+			if httpheader.NewDecoder().Decode(h, v) != nil {
+				errs++
+			}
+		}
+		if errs == 0 {
+			t.Errorf("Decode(%v) returned no error", h)
+		}
+	}
+}
+
+func TestGeneratedRoundTrip(t *testing.T) {
+	for _, v := range encodeTests {
+		h, err := httpheader.Header(v)
+		if err != nil {
+			t.Fatalf("Header returned error: %v", err)
+		}
+		want := reflect.New(reflect.Indirect(reflect.ValueOf(v)).Type()).Interface()
+		got := reflect.New(reflect.Indirect(reflect.ValueOf(v)).Type()).Interface()
+		wantErr := httpheader.NewDecoder().Decode(h, want)
+		if err := httpheader.Decode(h, got); !reflect.DeepEqual(err, wantErr) {
+			t.Errorf("Decode(%v) returned error %v, want %v", h, err, wantErr)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Decode(%v) = %+v, want %+v", h, got, want)
+		}
+	}
+}
+
+func TestGeneratedMarker(t *testing.T) {
+	for _, v := range []interface{ HTTPHeaderGenerated() interface{} }{Options{}, Nested{}, &Lists{}, Fields{}} {
+		want := reflect.PtrTo(reflect.Indirect(reflect.ValueOf(v)).Type())
+		if got := reflect.TypeOf(v.HTTPHeaderGenerated()); got != want {
+			t.Errorf("%T.HTTPHeaderGenerated returned %v, want %v", v, got, want)
+		}
+	}
+}
+
+func TestGeneratedEmbedded(t *testing.T) {
+	v := Wrapper{Options: Options{Name: "foo", RequestID: "id", Hops: intPtr(1)}, B: "b"}
+	h, err := httpheader.Header(v)
+	if err != nil {
+		t.Fatalf("Header returned error: %v", err)
+	}
+	if h.Get("Name") != "foo" || h.Get("X-B") != "b" {
+		t.Errorf("Header(%#v) = %v, want Name and X-B", v, h)
+	}
+
+	var got Wrapper
+	if err := httpheader.Decode(h, &got); err != nil {
+		t.Fatalf("Decode returned error: %v", err)
+	}
+	if got.Name != "foo" || got.B != "b" {
+		t.Errorf("Decode(%v) = %+v, want Name and B", h, got)
+	}
+}
+
+// outer is handled by reflection, with generated structs as named fields.
+type outer struct {
+	Lists   Lists   `header:"X-Lists"`
+	Wrapper Wrapper `header:"X-Wrapper"`
+	Other   string  `header:"X-Other"`
+}
+
+func TestGeneratedFields(t *testing.T) {
+	v := outer{
+		Lists:   Lists{Values: []string{"a"}, Page: Page{Size: 2}},
+		Wrapper: Wrapper{Options: Options{Name: "foo", RequestID: "id", Hops: intPtr(1)}, B: "b"},
+		Other:   "o",
+	}
+	h, err := httpheader.Header(v)
+	if err != nil {
+		t.Fatalf("Header returned error: %v", err)
+	}
+	if h.Get("X-Value") != "a" || h.Get("Name") != "foo" || h.Get("X-B") != "b" {
+		t.Errorf("Header(%#v) = %v, want X-Value, Name and X-B", v, h)
+	}
+
+	var got outer
+	d := httpheader.NewDecoder(httpheader.WithDisallowUnknownHeaders())
+	if err := d.Decode(h, &got); err != nil {
+		t.Fatalf("Decode returned error: %v", err)
+	}
+	if !reflect.DeepEqual(got.Lists.Values, v.Lists.Values) || got.Lists.Page != v.Lists.Page ||
+		got.Wrapper.Name != "foo" || got.Wrapper.B != "b" || got.Other != "o" {
+		t.Errorf("Decode(%v) = %+v, want %+v", h, got, v)
+	}
+
+	h = http.Header{"X-List-Page": {"?"}, "X-Sep": {"x"}, "X-Hops": {"x"}}
+	err = httpheader.NewDecoder(httpheader.WithAllErrors()).Decode(h, &outer{})
+	if errs, ok := err.(*httpheader.DecodeErrors); !ok || len(errs.Errors) != 4 {
+		t.Errorf("Decode(%v) returned %v, want 4 errors", h, err)
+	}
+
+	type tagged struct {
+		Lists Lists `json:"lists"`
+	}
+	h, err = httpheader.NewEncoder(httpheader.WithTagName("json")).Encode(tagged{Lists{Values: []string{"a"}}})
+	if err != nil || h.Get("Values") != "a" {
+		t.Errorf("Encode returned %v, %v, want Values a", h, err)
+	}
+}
@@ -0,0 +1,225 @@
+// Package gentest declares the types used to test the code generated by
+// httpheader-gen against the reflection-based encoding and decoding.
+package gentest
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//go:generate go run ../../cmd/httpheader-gen -type=Options,Nested,Lists,Fields -output=generated.go
+
+// Options covers the basic types and tag options.
+type Options struct {
+	Name      string
+	Alias     string  `header:"X-Alias,omitempty"`
+	Flag      bool    `header:"X-Flag"`
+	IntFlag   bool    `header:"X-Int-Flag,int"`
+	Count     int     `header:"X-Count,omitempty"`
+	Small     int8    `header:"X-Small"`
+	Port      uint16  `header:"X-Port"`
+	Size      uint64  `header:"X-Size,default=512"`
+	Ratio     float64 `header:"X-Ratio"`
+	Weight    float32 `header:"X-Weight,omitempty"`
+	Level     Level   `header:"X-Level"`
+	Mode      Mode    `header:"X-Mode"`
+	Color     Color   `header:"X-Color,omitempty"`
+	Token     *string `header:"X-Token"`
+	Retries   *int    `header:"X-Retries,omitempty"`
+	Modified  time.Time
+	Expires   time.Time     `header:"X-Expires,unix,omitempty"`
+	Created   time.Time     `header:"X-Created,rfc3339"`
+	Day       time.Time     `header:"X-Day,layout=2006-01-02"`
+	Timeout   time.Duration `header:"X-Timeout"`
+	Delay     time.Duration `header:"X-Delay,seconds,omitempty"`
+	Backoff   Backoff       `header:"X-Backoff"`
+	RequestID string        `header:"X-Request-Id,required"`
+	Proto     string        `header:"X-Forwarded-Proto,dup=unique"`
+	Via       string        `header:"Via,dup=join,sep=;"`
+	Hops      *int          `header:"X-Hops,dup=last"`
+	Part      *string       `header:"X-Part,dup=last,sep=;"`
+	Seen      time.Time     `header:"X-Seen,unix,dup=last"`
+	Extra     http.Header
+	Meta      map[string]string   `header:"X-Meta-,prefix"`
+	Tags      map[string][]string `header:"X-Tag-,prefix"`
+	Object    map[string]string   `header:"x-cos-meta-,prefix,raw"`
+	Lower     string              `header:"x-lower,raw,omitempty"`
+	Ignored   string              `header:"-"`
+	internal  string
+	Inner
+}
+
+// Inner is embedded in Options.
+type Inner struct {
+	Region string `header:"X-Region,default=eu"`
+}
+
+// Nested covers nested structs and the Encoder and Decoder interfaces.
+type Nested struct {
+	Options
+	Auth   Auth   `header:"Authorization"`
+	Limits Limits `header:"X-Limit,omitempty"`
+	Page   Page   `header:"X-Page"`
+}
+
+// Wrapper embeds Options, whose generated methods are promoted, without
+// methods of its own.
+type Wrapper struct {
+	Options
+	B string `header:"X-B"`
+}
+
+// Fields holds a generated struct and a struct embedding one as named
+// fields, which are handled like plain structs.
+type Fields struct {
+	Lists   Lists   `header:"X-Lists"`
+	Wrapper Wrapper `header:"X-Wrapper"`
+}
+
+// Limits is nested in Nested.
+type Limits struct {
+	Max int `header:"X-Limit-Max"`
+	Min int `header:"X-Limit-Min,omitempty"`
+}
+
+// Page is nested in Nested and has generated methods.
+type Page struct {
+	Size int `header:"X-Page-Size"`
+}
+
+// EncodeHeader implements the httpheader.Encoder interface.
+func (p Page) EncodeHeader(key string, header *http.Header) error {
+	if p.Size < 0 {
+		return errors.New("negative page size")
+	}
+	if *header == nil {
+		*header = make(http.Header)
+	}
+	header.Set(key, strings.Repeat("*", p.Size))
+	return nil
+}
+
+// DecodeHeader implements the httpheader.Decoder interface.
+func (p *Page) DecodeHeader(header http.Header, key string) error {
+	v := header.Get(key)
+	if strings.Trim(v, "*") != "" {
+		return errors.New("invalid page")
+	}
+	p.Size = len(v)
+	return nil
+}
+
+// Auth implements the httpheader.Encoder and httpheader.Decoder interfaces.
+type Auth struct {
+	User, Password string
+}
+
+// EncodeHeader implements the httpheader.Encoder interface.
+func (a Auth) EncodeHeader(key string, header *http.Header) error {
+	if a.User != "" {
+		header.Set(key, a.User+":"+a.Password)
+	}
+	return nil
+}
+
+// DecodeHeader implements the httpheader.Decoder interface.
+func (a *Auth) DecodeHeader(header http.Header, key string) error {
+	v := header.Get(key)
+	if v == "" {
+		return nil
+	}
+	i := strings.IndexByte(v, ':')
+	if i < 0 {
+		return errors.New("missing password")
+	}
+	a.User, a.Password = v[:i], v[i+1:]
+	return nil
+}
+
+// Lists covers the slice types and the list options.
+type Lists struct {
+	Values   []string        `header:"X-Value"`
+	Comma    []string        `header:"X-Comma,comma"`
+	Sep      []int           `header:"X-Sep,sep=;,omitempty"`
+	Numbers  []int           `header:"X-Number,default=1;2"`
+	Defaults []string        `header:"X-Default,sep=|,default=a|b"`
+	Levels   []Level         `header:"X-Levels,comma"`
+	Times    []time.Time     `header:"X-Time,unix"`
+	Delays   []time.Duration `header:"X-Delays,millis,comma"`
+	Colors   Colors          `header:"X-Colors"`
+	Bytes    []byte          `header:"X-Bytes"`
+	Flags    []bool          `header:"X-Flags,int"`
+	Page     Page            `header:"X-List-Page,required"`
+}
+
+// Level is a named integer type.
+type Level int
+
+// Mode implements fmt.Stringer.
+type Mode string
+
+// String implements fmt.Stringer.
+func (m Mode) String() string {
+	return "mode:" + string(m)
+}
+
+// Color implements encoding.TextMarshaler and encoding.TextUnmarshaler.
+type Color struct {
+	R, G, B uint8
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (c Color) MarshalText() ([]byte, error) {
+	return []byte{'#', hex[c.R>>4], hex[c.R&15], hex[c.G>>4], hex[c.G&15], hex[c.B>>4], hex[c.B&15]}, nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (c *Color) UnmarshalText(b []byte) error {
+	if len(b) != 7 || b[0] != '#' {
+		return errors.New("invalid color")
+	}
+	var rgb [3]uint8
+	for i := range rgb {
+		hi, lo := strings.IndexByte(hex, b[1+2*i]), strings.IndexByte(hex, b[2+2*i])
+		if hi < 0 || lo < 0 {
+			return errors.New("invalid color")
+		}
+		rgb[i] = uint8(hi<<4 | lo)
+	}
+	c.R, c.G, c.B = rgb[0], rgb[1], rgb[2]
+	return nil
+}
+
+const hex = "0123456789abcdef"
+
+// Colors implements encoding.TextMarshaler and encoding.TextUnmarshaler
+// with a pointer receiver.
+type Colors []Color
+
+// MarshalText implements encoding.TextMarshaler.
+func (c *Colors) MarshalText() ([]byte, error) {
+	var parts []string
+	for _, color := range *c {
+		b, _ := color.MarshalText()
+		parts = append(parts, string(b))
+	}
+	return []byte(strings.Join(parts, " ")), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (c *Colors) UnmarshalText(b []byte) error {
+	*c = nil
+	for _, s := range strings.Fields(string(b)) {
+		var color Color
+		if err := color.UnmarshalText([]byte(s)); err != nil {
+			return err
+		}
+		*c = append(*c, color)
+	}
+	return nil
+}
+
+// Backoff is a named time.Duration, encoded as an integer.
+type Backoff time.Duration
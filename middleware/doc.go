@@ -0,0 +1,3 @@
+// Package middleware provides net/http middleware decoding request Header
+// fields into structs with the httpheader package.
+package middleware
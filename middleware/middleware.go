@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/mozillazg/go-httpheader"
+)
+
+// contextKey is the context key of the values of type T stored by Decode.
+type contextKey[T any] struct{}
+
+// Option configures Decode.
+type Option func(*config)
+
+type config struct {
+	decoder      *httpheader.HeaderDecoder
+	errorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// WithDecoder sets the HeaderDecoder used by Decode, the one used by
+// httpheader.Decode by default.
+func WithDecoder(d *httpheader.HeaderDecoder) Option {
+	return func(c *config) {
+		c.decoder = d
+	}
+}
+
+// WithErrorHandler sets the function called by Decode when the request
+// Header can't be decoded, WriteProblem by default.
+func WithErrorHandler(fn func(w http.ResponseWriter, r *http.Request, err error)) Option {
+	return func(c *config) {
+		c.errorHandler = fn
+	}
+}
+
+// Decode returns a handler that decodes the Header of each request into a
+// struct of type T and calls next with the struct stored in the request
+// context, see FromContext. If the Header can't be decoded, including when
+// fields with the "required" option are missing, next is not called and the
+// error handler responds instead.
+//
+// Decode panics if T is not a struct or a map with string keys.
+func Decode[T any](next http.Handler, opts ...Option) http.Handler {
+	if t := reflect.TypeOf((*T)(nil)).Elem(); t.Kind() != reflect.Struct &&
+		(t.Kind() != reflect.Map || t.Key().Kind() != reflect.String) {
+		panic(fmt.Sprintf("middleware: Decode of %v, want a struct or a map with string keys", t))
+	}
+	c := config{errorHandler: WriteProblem}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var v T
+		var err error
+		if c.decoder != nil {
+			err = c.decoder.DecodeRequest(r, &v)
+		} else {
+			err = httpheader.DecodeRequest(r, &v)
+		}
+		if err != nil {
+			c.errorHandler(w, r, err)
+			return
+		}
+		ctx := context.WithValue(r.Context(), contextKey[T]{}, v)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// FromContext returns the struct of type T stored by Decode in ctx, and
+// whether there is one.
+func FromContext[T any](ctx context.Context) (T, bool) {
+	v, ok := ctx.Value(contextKey[T]{}).(T)
+	return v, ok
+}
+
+// Problem is the problem details body, as defined by RFC 9457, written by
+// WriteProblem.
+type Problem struct {
+	Type   string         `json:"type"`
+	Title  string         `json:"title"`
+	Status int            `json:"status"`
+	Detail string         `json:"detail,omitempty"`
+	Errors []ProblemError `json:"errors,omitempty"`
+}
+
+// ProblemError describes a Header field that could not be decoded.
+type ProblemError struct {
+	Header string `json:"header"`
+	Field  string `json:"field,omitempty"` // path of the struct field
+	Reason string `json:"reason"`
+}
+
+// WriteProblem responds to the request with status 400 and a Problem body
+// of type "application/problem+json" describing err, as returned by
+// httpheader.Decode. The Header field values, which may hold credentials,
+// are not written in the Detail of the Problem.
+func WriteProblem(w http.ResponseWriter, r *http.Request, err error) {
+	p := Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(http.StatusBadRequest),
+		Status: http.StatusBadRequest,
+		Detail: "The request header fields listed in errors are invalid.",
+		Errors: problemErrors(err),
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// problemErrors returns the Header fields described by err.
+func problemErrors(err error) []ProblemError {
+	errs, ok := err.(*httpheader.DecodeErrors)
+	if !ok {
+		errs = &httpheader.DecodeErrors{Errors: []error{err}}
+	}
+	var problems []ProblemError
+	for _, f := range errs.Fields() {
+		problems = append(problems, ProblemError(f))
+	}
+	return problems
+}
@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mozillazg/go-httpheader"
+)
+
+type requestHeader struct {
+	TenantID string `header:"X-Tenant-Id,required"`
+	Retry    int    `header:"X-Retry"`
+}
+
+func TestDecode(t *testing.T) {
+	var got requestHeader
+	var ok bool
+	h := Decode[requestHeader](http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok = FromContext[requestHeader](r.Context())
+	}))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Tenant-Id", "t1")
+	r.Header.Set("X-Retry", "3")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if want := (requestHeader{"t1", 3}); !ok || got != want {
+		t.Errorf("FromContext returned %#v, %v, want %#v, true", got, ok, want)
+	}
+
+	if _, ok := FromContext[int](r.Context()); ok {
+		t.Error("FromContext returned a value for a type that wasn't decoded")
+	}
+}
+
+func TestDecode_problem(t *testing.T) {
+	h := Decode[requestHeader](http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler called for an invalid request")
+	}))
+
+	tests := []struct {
+		header http.Header
+		want   []ProblemError
+	}{
+		{
+			http.Header{"X-Retry": {"3"}},
+			[]ProblemError{{Header: "X-Tenant-Id", Field: "TenantID", Reason: "missing"}},
+		},
+		{
+			http.Header{"X-Tenant-Id": {"t1"}, "X-Retry": {"a"}},
+			[]ProblemError{{Header: "X-Retry", Field: "Retry", Reason: `strconv.ParseInt: parsing "a": invalid syntax`}},
+		},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header = tt.header
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+			t.Errorf("Content-Type = %q, want application/problem+json", ct)
+		}
+		var p Problem
+		if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+			t.Fatalf("invalid problem body: %v", err)
+		}
+		if p.Status != http.StatusBadRequest || p.Detail == "" || !reflect.DeepEqual(p.Errors, tt.want) {
+			t.Errorf("problem = %#v, want errors %#v", p, tt.want)
+		}
+		if strings.Contains(p.Detail, `"a"`) {
+			t.Errorf("problem detail %q holds the header value", p.Detail)
+		}
+	}
+}
+
+func TestDecode_invalidType(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Decode[int] didn't panic")
+		}
+	}()
+	Decode[int](http.NotFoundHandler())
+}
+
+func TestDecode_options(t *testing.T) {
+	var gotErr error
+	h := Decode[requestHeader](http.NotFoundHandler(),
+		WithDecoder(httpheader.NewDecoder(httpheader.WithDisallowUnknownHeaders())),
+		WithErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+			gotErr = err
+			w.WriteHeader(http.StatusTeapot)
+		}))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Tenant-Id", "t1")
+	r.Header.Set("X-Unknown", "1")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusTeapot {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusTeapot)
+	}
+	if _, ok := gotErr.(*httpheader.UnknownHeaderError); !ok {
+		t.Errorf("error handler called with %#v, want *UnknownHeaderError", gotErr)
+	}
+}
+
+func TestDecode_allErrors(t *testing.T) {
+	h := Decode[requestHeader](http.NotFoundHandler(),
+		WithDecoder(httpheader.NewDecoder(httpheader.WithAllErrors())))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Retry", "a")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	var p Problem
+	if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+		t.Fatalf("invalid problem body: %v", err)
+	}
+	want := []ProblemError{
+		{Header: "X-Retry", Field: "Retry", Reason: `strconv.ParseInt: parsing "a": invalid syntax`},
+		{Header: "X-Tenant-Id", Field: "TenantID", Reason: "missing"},
+	}
+	if w.Code != http.StatusBadRequest || !reflect.DeepEqual(p.Errors, want) {
+		t.Errorf("status = %d, problem errors = %#v, want %#v", w.Code, p.Errors, want)
+	}
+}
+
+func TestDecode_duplicate(t *testing.T) {
+	h := Decode[requestHeader](http.NotFoundHandler(),
+		WithDecoder(httpheader.NewDecoder(httpheader.WithDuplicates(httpheader.DuplicateUnique))))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header["X-Tenant-Id"] = []string{"a", "b"}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	var p Problem
+	if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+		t.Fatalf("invalid problem body: %v", err)
+	}
+	want := []ProblemError{{Header: "X-Tenant-Id", Field: "TenantID", Reason: "duplicate"}}
+	if w.Code != http.StatusBadRequest || !reflect.DeepEqual(p.Errors, want) {
+		t.Errorf("status = %d, problem errors = %#v, want %#v", w.Code, p.Errors, want)
+	}
+}
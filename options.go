@@ -0,0 +1,256 @@
+package httpheader
+
+import (
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/mozillazg/go-httpheader/internal/fieldtag"
+)
+
+// Option configures a HeaderEncoder or a HeaderDecoder, see NewEncoder and
+// NewDecoder.
+type Option func(*config)
+
+// config holds the options shared by HeaderEncoder and HeaderDecoder.
+type config struct {
+	tagName     string
+	timeLayout  string
+	strict      bool
+	keyFunc     func(string) string
+	keyMode     KeyMode
+	converters  converters
+	consume     bool
+	defaultSep  string
+	omitDefault bool
+	sanitize    SanitizeMode
+
+	disallowUnknown bool
+	allowedHeaders  map[string]bool
+	allErrors       bool
+	filter          func(name string) bool
+	duplicates      DuplicatePolicy
+
+	kindOf     kindFunc
+	fieldCache sync.Map // map[reflect.Type][]field
+}
+
+func (c *config) init(kindOf kindFunc, opts []Option) {
+	c.kindOf = kindOf
+	c.tagName = tagName
+	c.timeLayout = http.TimeFormat
+	c.defaultSep = fieldtag.DefaultSeparator
+	for _, opt := range opts {
+		opt(c)
+	}
+}
+
+// raw reports whether the Header field names of f are used verbatim, see
+// WithKeyMode and the "raw" option.
+func (c *config) raw(f *field) bool {
+	return f.raw || c.keyMode != KeyCanonical
+}
+
+// key returns the Header field name of f.
+func (c *config) key(f *field) string {
+	if c.raw(f) {
+		return f.name
+	}
+	if c.keyFunc != nil {
+		return c.keyFunc(f.name)
+	}
+	return f.key
+}
+
+// canonicalKey returns the canonical format of the Header field name s.
+func (c *config) canonicalKey(s string) string {
+	if c.keyMode != KeyCanonical {
+		return s
+	}
+	if c.keyFunc != nil {
+		return c.keyFunc(s)
+	}
+	return http.CanonicalHeaderKey(s)
+}
+
+// fieldKey returns the Header field name s, encoded from field f, in
+// canonical format unless f is raw.
+func (c *config) fieldKey(f *field, s string) string {
+	if f.raw {
+		return s
+	}
+	return c.canonicalKey(s)
+}
+
+// WithTagName sets the struct field tag key, "header" by default.
+func WithTagName(name string) Option {
+	return func(c *config) {
+		c.tagName = name
+	}
+}
+
+// WithTimeLayout sets the layout of time.Time values that have neither the
+// "unix" option nor another time option, http.TimeFormat by default.
+func WithTimeLayout(layout string) Option {
+	return func(c *config) {
+		c.timeLayout = layout
+	}
+}
+
+// WithStrict reports values that can't be represented as a Header field
+// value as an *UnsupportedTypeError instead of falling back to their default
+// string representation when encoding, or ignoring them when decoding.
+// Boolean values are also decoded with strconv.ParseBool, so any value other
+// than "1", "t", "T", "TRUE", "true", "True", "0", "f", "F", "FALSE",
+// "false" and "False" is an error.
+func WithStrict() Option {
+	return func(c *config) {
+		c.strict = true
+	}
+}
+
+// WithKeyCanonicalizer sets the function used to turn field names into
+// Header field names, textproto.CanonicalMIMEHeaderKey by default.
+func WithKeyCanonicalizer(fn func(string) string) Option {
+	return func(c *config) {
+		c.keyFunc = fn
+	}
+}
+
+// KeyMode selects how Header field names are written and looked up, see
+// WithKeyMode.
+type KeyMode int
+
+const (
+	// KeyCanonical writes and looks up the canonical format of the Header
+	// field names, see WithKeyCanonicalizer. This is the default.
+	KeyCanonical KeyMode = iota
+	// KeyExact writes and looks up the Header field names verbatim,
+	// matching the prefixes of the "prefix" option case-sensitively too.
+	KeyExact
+	// KeyFold writes the Header field names verbatim and looks them up
+	// ignoring case, merging the values of the Header fields whose names
+	// only differ in case.
+	KeyFold
+)
+
+// WithKeyMode sets how Header field names are written by a HeaderEncoder
+// and looked up by a HeaderDecoder. The KeyExact and KeyFold modes use the
+// names as given by the tags, the keys of maps and http.Header values,
+// instead of canonicalizing them as http.Header.Add and http.Header.Get do,
+// e.g. for case-sensitive object metadata keys or lower-case HTTP/2 Header
+// fields. The "raw" option does the same for a single field.
+func WithKeyMode(mode KeyMode) Option {
+	return func(c *config) {
+		c.keyMode = mode
+	}
+}
+
+// WithConverter registers a converter for type t, see RegisterConverter.
+func WithConverter(t reflect.Type, enc EncodeFunc, dec DecodeFunc) Option {
+	return func(c *config) {
+		c.converters.register(t, enc, dec)
+	}
+}
+
+// WithConsume makes a HeaderDecoder decode fields sharing the same Header
+// field name from successive values, see DecodeConsume.
+func WithConsume() Option {
+	return func(c *config) {
+		c.consume = true
+	}
+}
+
+// WithDefaultSeparator sets the separator of the values of the "default"
+// option of slice and array fields, ";" by default.
+func WithDefaultSeparator(sep string) Option {
+	return func(c *config) {
+		c.defaultSep = sep
+	}
+}
+
+// WithOmitDefault makes a HeaderEncoder omit fields whose encoded values are
+// those of their "default" option.
+func WithOmitDefault() Option {
+	return func(c *config) {
+		c.omitDefault = true
+	}
+}
+
+// WithSanitize sets how a HeaderEncoder handles Header field names and
+// values that are not valid, see InvalidHeaderError. By default they are
+// rejected.
+func WithSanitize(mode SanitizeMode) Option {
+	return func(c *config) {
+		c.sanitize = mode
+	}
+}
+
+// WithDisallowUnknownHeaders makes a HeaderDecoder return an
+// *UnknownHeaderError listing the Header fields that are not decoded into
+// any struct field, including the fields of embedded and nested structs and
+// "prefix" maps. Hop-by-hop fields such as "Connection", and fields set by
+// HTTP clients, servers and proxies such as "User-Agent" or
+// "X-Forwarded-For", are allowed as well as the names given. Structs with an
+// http.Header field collecting the other Header fields have none unknown.
+func WithDisallowUnknownHeaders(allowed ...string) Option {
+	return func(c *config) {
+		c.disallowUnknown = true
+		if c.allowedHeaders == nil {
+			c.allowedHeaders = make(map[string]bool)
+			for _, k := range transportHeaders {
+				c.allowedHeaders[k] = true
+			}
+		}
+		for _, k := range allowed {
+			c.allowedHeaders[http.CanonicalHeaderKey(k)] = true
+		}
+	}
+}
+
+// WithAllErrors makes a HeaderDecoder decode every struct field even when
+// some fail, returning a *DecodeErrors listing all the errors found instead
+// of the first one.
+func WithAllErrors() Option {
+	return func(c *config) {
+		c.allErrors = true
+	}
+}
+
+// WithDuplicates sets how a HeaderDecoder decodes the fields holding a single
+// value, such as strings, numbers and times, when their Header field has
+// more than one value. By default the first value is decoded. The "dup"
+// option of a field takes precedence, see Decode.
+func WithDuplicates(policy DuplicatePolicy) Option {
+	return func(c *config) {
+		c.duplicates = policy
+	}
+}
+
+// WithHeaderFilter makes a HeaderDecoder ignore the Header fields for which
+// keep returns false, as if they were missing, when decoding structs as well
+// as maps. keep is called with the Header field names as found in the
+// decoded http.Header. For example, to log the Header fields but the
+// credentials:
+//
+//	d := NewDecoder(WithHeaderFilter(func(name string) bool {
+//		name = http.CanonicalHeaderKey(name)
+//		return name != "Authorization" && name != "Cookie"
+//	}))
+//	var m map[string][]string
+//	err := d.Decode(r.Header, &m)
+func WithHeaderFilter(keep func(name string) bool) Option {
+	return func(c *config) {
+		c.filter = keep
+	}
+}
+
+// UnsupportedTypeError is returned in strict mode when a value can't be
+// encoded or decoded.
+type UnsupportedTypeError struct {
+	Type reflect.Type
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return "httpheader: unsupported type: " + e.Type.String()
+}
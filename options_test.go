@@ -0,0 +1,479 @@
+package httpheader
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type point struct {
+	X, Y int
+}
+
+func encodePoint(v reflect.Value) (string, error) {
+	p := v.Interface().(point)
+	return fmt.Sprintf("%d;%d", p.X, p.Y), nil
+}
+
+func decodePoint(s string) (reflect.Value, error) {
+	var p point
+	if _, err := fmt.Sscanf(s, "%d;%d", &p.X, &p.Y); err != nil {
+		return reflect.Value{}, err
+	}
+	return reflect.ValueOf(p), nil
+}
+
+func TestHeaderEncoder_options(t *testing.T) {
+	timeVal := time.Date(2000, 1, 1, 12, 34, 56, 0, time.UTC)
+	type options struct {
+		A    string            `hdr:"x-a"`
+		B    time.Time         `hdr:"x-b"`
+		C    point             `hdr:"x-c"`
+		D    []point           `hdr:"x-d"`
+		Meta map[string]string `hdr:"x-meta-,prefix"`
+		H    http.Header
+	}
+	s := options{
+		A:    "a",
+		B:    timeVal,
+		C:    point{1, 2},
+		D:    []point{{3, 4}},
+		Meta: map[string]string{"Foo": "bar"},
+		H:    http.Header{"X-H": []string{"h"}},
+	}
+
+	e := NewEncoder(
+		WithTagName("hdr"),
+		WithTimeLayout(time.RFC3339),
+		WithKeyCanonicalizer(strings.ToLower),
+		WithConverter(reflect.TypeOf(point{}), encodePoint, nil),
+	)
+	v, err := e.Encode(s)
+	if err != nil {
+		t.Errorf("Encode(%+v) returned error: %v", s, err)
+	}
+	want := http.Header{
+		"x-a":        []string{"a"},
+		"x-b":        []string{"2000-01-01T12:34:56Z"},
+		"x-c":        []string{"1;2"},
+		"x-d":        []string{"3;4"},
+		"x-meta-foo": []string{"bar"},
+		"x-h":        []string{"h"},
+	}
+	if !reflect.DeepEqual(want, v) {
+		t.Errorf("Encode(%+v) returned %v, want %v", s, v, want)
+	}
+
+	// the default encoder doesn't share the field cache of e
+	v, err = Header(struct {
+		A string `hdr:"x-a"`
+	}{"a"})
+	if err != nil {
+		t.Errorf("Header returned error: %v", err)
+	}
+	if want := (http.Header{"A": []string{"a"}}); !reflect.DeepEqual(want, v) {
+		t.Errorf("Header returned %v, want %v", v, want)
+	}
+}
+
+func TestHeaderEncoder_strict(t *testing.T) {
+	var nilInterface interface{}
+	tests := []struct {
+		in      interface{}
+		want    http.Header
+		wantErr bool
+	}{
+		{struct{ A map[string]int }{}, nil, true},
+		{struct{ A func() }{}, nil, true},
+		{struct{ A []struct{ B int } }{[]struct{ B int }{{1}}}, nil, true},
+		{struct{ A interface{} }{map[string]string{}}, nil, true},
+		{struct{ A interface{} }{nilInterface}, http.Header{"A": []string{""}}, false},
+		{struct{ A interface{} }{1}, http.Header{"A": []string{"1"}}, false},
+	}
+	e := NewEncoder(WithStrict())
+	for i, tt := range tests {
+		v, err := e.Encode(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%d. Encode(%+v) returned error %v, wantErr %v", i, tt.in, err, tt.wantErr)
+		}
+		if _, ok := err.(*UnsupportedTypeError); err != nil && !ok {
+			t.Errorf("%d. Encode(%+v) returned %#v, want *UnsupportedTypeError", i, tt.in, err)
+		}
+		if !tt.wantErr && !reflect.DeepEqual(tt.want, v) {
+			t.Errorf("%d. Encode(%+v) returned %v, want %v", i, tt.in, v, tt.want)
+		}
+	}
+}
+
+func TestHeaderDecoder_options(t *testing.T) {
+	timeVal := time.Date(2000, 1, 1, 12, 34, 56, 0, time.UTC)
+	type options struct {
+		A    string            `hdr:"x-a"`
+		B    time.Time         `hdr:"x-b"`
+		C    point             `hdr:"x-c"`
+		D    []point           `hdr:"x-d"`
+		Meta map[string]string `hdr:"x-meta-,prefix"`
+		Skip string            `header:"X-A"`
+	}
+	h := http.Header{
+		"x-a":        []string{"a"},
+		"X-A":        []string{"canonical"},
+		"x-b":        []string{"2000-01-01T12:34:56Z"},
+		"x-c":        []string{"1;2"},
+		"x-d":        []string{"3;4", "5;6"},
+		"x-meta-foo": []string{"bar"},
+	}
+	want := options{
+		A:    "a",
+		B:    timeVal,
+		C:    point{1, 2},
+		D:    []point{{3, 4}, {5, 6}},
+		Meta: map[string]string{"foo": "bar"},
+	}
+
+	d := NewDecoder(
+		WithTagName("hdr"),
+		WithTimeLayout(time.RFC3339),
+		WithKeyCanonicalizer(strings.ToLower),
+		WithConverter(reflect.TypeOf(point{}), nil, decodePoint),
+	)
+	var got options
+	if err := d.Decode(h, &got); err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want/got:\n%#v\n%#v", want, got)
+	}
+
+	h = http.Header{"x-c": []string{"1"}}
+	err := d.Decode(h, &got)
+	if e, ok := err.(*DecodeError); !ok || e.Field != "C" {
+		t.Errorf("Decode returned %#v, want *DecodeError for field C", err)
+	}
+}
+
+func TestHeaderDecoder_strict(t *testing.T) {
+	type strictStruct struct {
+		Bool    bool
+		BoolInt bool `header:",int"`
+		Func    func()
+		H       http.Header
+	}
+	tests := []struct {
+		in      http.Header
+		want    strictStruct
+		wantErr bool
+	}{
+		{http.Header{"Bool": []string{"True"}, "Boolint": []string{"0"}}, strictStruct{Bool: true}, false},
+		{http.Header{"Bool": []string{"yes"}}, strictStruct{}, true},
+		{http.Header{"Boolint": []string{"2"}}, strictStruct{}, true},
+		{http.Header{"Func": []string{"f"}}, strictStruct{}, true},
+		{http.Header{"H": []string{"h"}}, strictStruct{H: http.Header{"H": []string{"h"}}}, false},
+	}
+	d := NewDecoder(WithStrict())
+	for i, tt := range tests {
+		var got strictStruct
+		err := d.Decode(tt.in, &got)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%d. Decode(%v) returned error %v, wantErr %v", i, tt.in, err, tt.wantErr)
+		}
+		if !reflect.DeepEqual(tt.want, got) {
+			t.Errorf("%d. Decode(%v) got %#v, want %#v", i, tt.in, got, tt.want)
+		}
+	}
+
+	err := d.Decode(http.Header{"Func": []string{"f"}}, &strictStruct{})
+	if e, ok := err.(*DecodeError); !ok {
+		t.Errorf("Decode returned %#v, want *DecodeError", err)
+	} else if _, ok := e.Err.(*UnsupportedTypeError); !ok {
+		t.Errorf("DecodeError.Err = %#v, want *UnsupportedTypeError", e.Err)
+	}
+}
+
+func TestHeaderDecoder_consume(t *testing.T) {
+	h := http.Header{"C": []string{"foo", "bar"}}
+	var got D
+	if err := NewDecoder(WithConsume()).Decode(h, &got); err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+	if want := (D{B: B{C: "bar"}, C: "foo"}); !reflect.DeepEqual(want, got) {
+		t.Errorf("want/got:\n%#v\n%#v", want, got)
+	}
+}
+
+func TestHeaderDecoder_disallowUnknownHeaders(t *testing.T) {
+	type Inner struct {
+		Retry int `header:"X-Retry"`
+	}
+	type unknownStruct struct {
+		Inner
+		Name   string            `header:"X-Name"`
+		Nested Inner             `header:",omitempty"`
+		Meta   map[string]string `header:"X-Meta-,prefix"`
+	}
+	d := NewDecoder(WithDisallowUnknownHeaders("X-Request-Id"))
+
+	h := http.Header{
+		"X-Name":          []string{"foo"},
+		"X-Retry":         []string{"3"},
+		"Nested":          []string{"1"},
+		"X-Meta-Author":   []string{"bar"},
+		"X-Request-Id":    []string{"1"},
+		"Connection":      []string{"close"},
+		"User-Agent":      []string{"test"},
+		"X-Forwarded-For": []string{"127.0.0.1"},
+		"X-Empty":         nil,
+	}
+	if err := d.Decode(h, &unknownStruct{}); err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+
+	h["X-Nmae"] = []string{"typo"}
+	h["x-other"] = []string{"other"}
+	err := d.Decode(h, &unknownStruct{})
+	want := &UnknownHeaderError{Struct: "unknownStruct", Headers: []string{"X-Nmae", "x-other"}}
+	if !reflect.DeepEqual(err, want) {
+		t.Errorf("Decode returned %#v, want %#v", err, want)
+	}
+	if err := NewDecoder().Decode(h, &unknownStruct{}); err != nil {
+		t.Errorf("Decode without the option returned error: %v", err)
+	}
+}
+
+func TestHeaderDecoder_duplicates(t *testing.T) {
+	type dupStruct struct {
+		A     string   `header:"X-A"`
+		B     int      `header:"X-B,dup=first"`
+		List  []string `header:"X-A"`
+		Other string   `header:"X-C"`
+	}
+	h := http.Header{"X-A": {"a", "b"}, "X-B": {"1", "2"}, "X-C": {"c", "d"}}
+
+	var got dupStruct
+	if err := NewDecoder(WithDuplicates(DuplicateLast)).Decode(h, &got); err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+	want := dupStruct{A: "b", B: 1, List: []string{"a", "b"}, Other: "d"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want/got:\n%#v\n%#v", want, got)
+	}
+
+	got = dupStruct{}
+	if err := NewDecoder(WithDuplicates(DuplicateJoin)).Decode(h, &got); err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+	want = dupStruct{A: "a, b", B: 1, List: []string{"a", "b"}, Other: "c, d"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want/got:\n%#v\n%#v", want, got)
+	}
+
+	err := NewDecoder(WithDuplicates(DuplicateUnique), WithAllErrors()).Decode(h, &dupStruct{})
+	wantFields := []FieldError{
+		{Header: "X-A", Field: "A", Reason: "duplicate"},
+		{Header: "X-C", Field: "Other", Reason: "duplicate"},
+	}
+	if errs, ok := err.(*DecodeErrors); !ok || !reflect.DeepEqual(errs.Fields(), wantFields) {
+		t.Errorf("Decode returned error %v, want duplicate X-A and X-C", err)
+	}
+
+	// only the decoded Header field values are consumed
+	h = http.Header{"X-A": {"a", "b"}}
+	got = dupStruct{}
+	if err := NewDecoder(WithDuplicates(DuplicateLast), WithConsume()).Decode(h, &got); err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+	if got.A != "b" || !reflect.DeepEqual(got.List, []string{"a"}) {
+		t.Errorf("Decode returned %#v, want A b and List a", got)
+	}
+
+	var consumed struct {
+		S  string `header:"X-S,dup=last"`
+		S2 string `header:"X-S"`
+		J  string `header:"X-J,dup=join"`
+		J2 string `header:"X-J"`
+	}
+	h = http.Header{"X-S": {"a", "b", "c"}, "X-J": {"a", "b"}}
+	if err := NewDecoder(WithConsume()).Decode(h, &consumed); err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+	if consumed.S != "c" || consumed.S2 != "a" || consumed.J != "a, b" || consumed.J2 != "" {
+		t.Errorf("Decode returned %+v, want S c, S2 a, J a, b and no J2", consumed)
+	}
+
+	var typo struct {
+		A string `header:"X-A,dup=uniqe"`
+	}
+	if err := Decode(h, &typo); err == nil || !strings.Contains(err.Error(), `"uniqe"`) {
+		t.Errorf("Decode returned error %v, want unknown dup option", err)
+	}
+	if _, err := Header(typo); err == nil {
+		t.Error("Header returned no error for an unknown dup option")
+	}
+}
+
+func TestHeaderEncoder_keyMode(t *testing.T) {
+	type keyStruct struct {
+		A    string            `header:"x-a"`
+		Meta map[string]string `header:"x-cos-meta-,prefix"`
+		H    http.Header
+	}
+	s := keyStruct{
+		A:    "a",
+		Meta: map[string]string{"MyKey": "v"},
+		H:    http.Header{"x-h": {"h"}},
+	}
+	v, err := NewEncoder(WithKeyMode(KeyExact)).Encode(s)
+	if err != nil {
+		t.Errorf("Encode(%+v) returned error: %v", s, err)
+	}
+	want := http.Header{"x-a": {"a"}, "x-cos-meta-MyKey": {"v"}, "x-h": {"h"}}
+	if !reflect.DeepEqual(want, v) {
+		t.Errorf("Encode(%+v) returned %v, want %v", s, v, want)
+	}
+
+	m := map[string]string{"x-Mixed-CASE": "m"}
+	v, err = NewEncoder(WithKeyMode(KeyFold)).Encode(m)
+	if err != nil || !reflect.DeepEqual(v, http.Header{"x-Mixed-CASE": {"m"}}) {
+		t.Errorf("Encode(%v) returned %v, %v", m, v, err)
+	}
+}
+
+func TestHeaderDecoder_keyMode(t *testing.T) {
+	type keyStruct struct {
+		A    string            `header:"x-a"`
+		B    []string          `header:"x-b"`
+		Meta map[string]string `header:"x-cos-meta-,prefix"`
+		Rest http.Header
+	}
+	h := http.Header{
+		"x-a":              {"exact"},
+		"X-A":              {"canonical"},
+		"x-B":              {"1"},
+		"X-B":              {"2"},
+		"x-cos-meta-MyKey": {"v"},
+		"X-Cos-Meta-Other": {"c"},
+		"Other":            {"o"},
+	}
+
+	var got keyStruct
+	if err := NewDecoder(WithKeyMode(KeyExact)).Decode(h, &got); err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+	want := keyStruct{
+		A:    "exact",
+		Meta: map[string]string{"MyKey": "v"},
+		Rest: http.Header{"X-A": {"canonical"}, "x-B": {"1"}, "X-B": {"2"}, "X-Cos-Meta-Other": {"c"}, "Other": {"o"}},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want/got:\n%#v\n%#v", want, got)
+	}
+
+	var prefixed struct {
+		Meta map[string]string `header:"x-cos-meta-,prefix,required"`
+	}
+	err := NewDecoder(WithKeyMode(KeyExact)).Decode(http.Header{"X-Cos-Meta-A": {"a"}}, &prefixed)
+	if _, ok := err.(*MissingHeaderError); !ok || prefixed.Meta != nil {
+		t.Errorf("Decode returned %+v, %v, want *MissingHeaderError", prefixed, err)
+	}
+
+	got = keyStruct{}
+	if err := NewDecoder(WithKeyMode(KeyFold)).Decode(h, &got); err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+	want = keyStruct{
+		A:    "canonical",
+		B:    []string{"2", "1"},
+		Meta: map[string]string{"MyKey": "v", "Other": "c"},
+		Rest: http.Header{"Other": {"o"}},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want/got:\n%#v\n%#v", want, got)
+	}
+
+	type foldStruct struct {
+		A string `header:"x-a,required"`
+	}
+	d := NewDecoder(WithKeyMode(KeyFold), WithDisallowUnknownHeaders())
+	if err := d.Decode(http.Header{"X-A": {"a"}}, &foldStruct{}); err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+	h = http.Header{"X-A": {"a", "b"}, "x-a": {"c"}}
+	var consumed struct {
+		A string `header:"x-a"`
+		B string `header:"X-a"`
+		C string `header:"X-A"`
+	}
+	if err := NewDecoder(WithKeyMode(KeyFold), WithConsume()).Decode(h, &consumed); err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+	if consumed.A != "a" || consumed.B != "b" || consumed.C != "c" {
+		t.Errorf("Decode returned %+v, want successive values", consumed)
+	}
+}
+
+func TestHeaderDecoder_allErrors(t *testing.T) {
+	type Inner struct {
+		Retry int `header:"X-Retry"`
+	}
+	type allErrorsStruct struct {
+		Name    string    `header:"X-Name,required"`
+		Count   int       `header:"X-Count"`
+		Tags    []int     `header:"X-Tag"`
+		Limit   *uint     `header:"X-Limit"`
+		Expires time.Time `header:"X-Expires,required"`
+		Inner   Inner     `header:",omitempty"`
+	}
+	d := NewDecoder(WithAllErrors(), WithDisallowUnknownHeaders())
+
+	h := http.Header{
+		"X-Count":   []string{"a"},
+		"X-Tag":     []string{"1", "b"},
+		"X-Limit":   []string{"-1"},
+		"Inner":     []string{"1"},
+		"X-Retry":   []string{"c"},
+		"X-Unknown": []string{"1"},
+	}
+	var v allErrorsStruct
+	err := d.Decode(h, &v)
+	errs, ok := err.(*DecodeErrors)
+	if !ok {
+		t.Fatalf("Decode returned %#v, want *DecodeErrors", err)
+	}
+	if errs.Struct != "allErrorsStruct" || len(errs.Errors) != 6 || len(errs.Unwrap()) != 6 {
+		t.Errorf("Decode returned %#v, want 6 errors", errs)
+	}
+	want := []FieldError{
+		{Header: "X-Count", Field: "Count", Reason: `strconv.ParseInt: parsing "a": invalid syntax`},
+		{Header: "X-Tag", Field: "Tags", Reason: `strconv.ParseInt: parsing "b": invalid syntax`},
+		{Header: "X-Limit", Field: "Limit", Reason: `strconv.ParseUint: parsing "-1": invalid syntax`},
+		{Header: "X-Retry", Field: "Inner.Retry", Reason: `strconv.ParseInt: parsing "c": invalid syntax`},
+		{Header: "X-Name", Field: "Name", Reason: "missing"},
+		{Header: "X-Expires", Field: "Expires", Reason: "missing"},
+		{Header: "X-Unknown", Reason: "unknown"},
+	}
+	if got := errs.Fields(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Fields returned %#v, want %#v", got, want)
+	}
+	if msg := err.Error(); !strings.HasPrefix(msg, "httpheader: 6 errors: cannot decode header X-Count") ||
+		strings.Count(msg, "httpheader: ") != 1 {
+		t.Errorf("Error returned %q", msg)
+	}
+	if v.Limit != nil {
+		t.Errorf("Limit = %v, want nil", *v.Limit)
+	}
+
+	// a single error is reported as a *DecodeErrors too
+	h = http.Header{"X-Name": {"foo"}, "X-Expires": {"Mon, 02 Jan 2006 15:04:05 GMT"}, "X-Count": {"a"}}
+	err = d.Decode(h, &allErrorsStruct{})
+	if errs, ok := err.(*DecodeErrors); !ok || len(errs.Errors) != 1 || err.Error() != errs.Errors[0].Error() {
+		t.Errorf("Decode returned %#v, want one error", err)
+	}
+
+	h["X-Count"] = []string{"1"}
+	if err := d.Decode(h, &allErrorsStruct{}); err != nil {
+		t.Errorf("Decode returned error: %v", err)
+	}
+}
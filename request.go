@@ -0,0 +1,137 @@
+package httpheader
+
+import (
+	"net/http"
+	"reflect"
+)
+
+// MergeMode selects how encoded Header fields are merged into a Header that
+// may already hold some of them.
+type MergeMode int
+
+const (
+	// MergeAdd appends the encoded values to the existing ones, like
+	// http.Header.Add.
+	MergeAdd MergeMode = iota
+	// MergeSet replaces the existing values of the encoded Header fields,
+	// like http.Header.Set. Fields encoded as multiple values keep all of
+	// them.
+	MergeSet
+	// MergeKeepExisting skips the encoded Header fields that are already
+	// present.
+	MergeKeepExisting
+)
+
+// headerSink is a Header that encoded fields are merged into.
+type headerSink struct {
+	dst     *http.Header // allocated by put if nil
+	prefix  string       // prepended to the Header field names
+	mode    MergeMode
+	touched map[string]bool // Header fields merged so far, unless mode is MergeAdd
+}
+
+// put merges value into the Header field key.
+func (s *headerSink) put(key, value string) {
+	if *s.dst == nil {
+		*s.dst = make(http.Header)
+	}
+	h := *s.dst
+	key = s.prefix + key
+	if s.mode != MergeAdd && !s.touched[key] {
+		if len(h[key]) > 0 {
+			if s.mode == MergeKeepExisting {
+				return
+			}
+			delete(h, key)
+		}
+		if s.touched == nil {
+			s.touched = make(map[string]bool)
+		}
+		s.touched[key] = true
+	}
+	addValue(h, key, value)
+}
+
+// noTrailer holds no trailer field, it must not be modified.
+var noTrailer = http.Header{}
+
+// trailerOf returns trailer, or noTrailer if it is nil, so that the fields
+// with the "trailer" option are not decoded from the Header.
+func trailerOf(trailer http.Header) http.Header {
+	if trailer == nil {
+		return noTrailer
+	}
+	return trailer
+}
+
+// DecodeRequest parses the Header of r into the struct v, see Decode. The
+// fields with the "trailer" option are decoded from the trailer of r, which
+// is only available once its body has been read.
+func DecodeRequest(r *http.Request, v interface{}) error {
+	return defaultDecoder.DecodeRequest(r, v)
+}
+
+// DecodeResponse parses the Header of resp into the struct v, see Decode.
+// The fields with the "trailer" option are decoded from the trailer of resp,
+// which is only available once its body has been read.
+func DecodeResponse(resp *http.Response, v interface{}) error {
+	return defaultDecoder.DecodeResponse(resp, v)
+}
+
+// DecodeRequest parses the Header of r into the struct v, see the
+// DecodeRequest function.
+func (d *HeaderDecoder) DecodeRequest(r *http.Request, v interface{}) error {
+	return d.decodePtr(r.Header, trailerOf(r.Trailer), v)
+}
+
+// DecodeResponse parses the Header of resp into the struct v, see the
+// DecodeResponse function.
+func (d *HeaderDecoder) DecodeResponse(resp *http.Response, v interface{}) error {
+	return d.decodePtr(resp.Header, trailerOf(resp.Trailer), v)
+}
+
+// EncodeToRequest merges the encoding of v into the Header of req according
+// to mode, see Header. The fields with the "trailer" option are merged into
+// the trailer of req, which must be done before sending it. The fields
+// encoded before an error is returned are left in req.
+func EncodeToRequest(req *http.Request, v interface{}, mode MergeMode) error {
+	return defaultEncoder.EncodeToRequest(req, v, mode)
+}
+
+// EncodeToResponse is like EncodeToRequest for resp.
+func EncodeToResponse(resp *http.Response, v interface{}, mode MergeMode) error {
+	return defaultEncoder.EncodeToResponse(resp, v, mode)
+}
+
+// EncodeToResponseWriter is like EncodeToRequest for the Header of w, which
+// must be done before calling its WriteHeader or Write method. The fields
+// with the "trailer" option may be encoded later on, until the handler
+// returns, as they are added with the http.TrailerPrefix prefix.
+func EncodeToResponseWriter(w http.ResponseWriter, v interface{}, mode MergeMode) error {
+	return defaultEncoder.EncodeToResponseWriter(w, v, mode)
+}
+
+// EncodeToRequest merges the encoding of v into the Header of req, see the
+// EncodeToRequest function.
+func (e *HeaderEncoder) EncodeToRequest(req *http.Request, v interface{}, mode MergeMode) error {
+	return e.encodeInto(reflect.ValueOf(v),
+		&headerSink{dst: &req.Header, mode: mode},
+		&headerSink{dst: &req.Trailer, mode: mode})
+}
+
+// EncodeToResponse merges the encoding of v into the Header of resp, see the
+// EncodeToRequest function.
+func (e *HeaderEncoder) EncodeToResponse(resp *http.Response, v interface{}, mode MergeMode) error {
+	return e.encodeInto(reflect.ValueOf(v),
+		&headerSink{dst: &resp.Header, mode: mode},
+		&headerSink{dst: &resp.Trailer, mode: mode})
+}
+
+// EncodeToResponseWriter merges the encoding of v into the Header of w, see
+// the EncodeToResponseWriter function.
+func (e *HeaderEncoder) EncodeToResponseWriter(w http.ResponseWriter, v interface{}, mode MergeMode) error {
+	h := w.Header()
+	return e.encodeInto(reflect.ValueOf(v),
+		&headerSink{dst: &h, mode: mode},
+		&headerSink{dst: &h, prefix: http.TrailerPrefix, mode: mode})
+}
@@ -0,0 +1,119 @@
+package httpheader
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type mergeStruct struct {
+	Name     string   `header:"X-Name"`
+	Tags     []string `header:"X-Tag"`
+	Checksum string   `header:"X-Checksum,trailer,omitempty"`
+}
+
+func TestEncodeToRequest(t *testing.T) {
+	s := mergeStruct{Name: "foo", Tags: []string{"a", "b"}, Checksum: "abc"}
+	tests := []struct {
+		mode        MergeMode
+		want        http.Header
+		wantTrailer http.Header
+	}{
+		{
+			MergeAdd,
+			http.Header{"X-Name": {"old", "foo"}, "X-Tag": {"old", "a", "b"}, "X-Other": {"old"}},
+			http.Header{"X-Checksum": {"old", "abc"}},
+		},
+		{
+			MergeSet,
+			http.Header{"X-Name": {"foo"}, "X-Tag": {"a", "b"}, "X-Other": {"old"}},
+			http.Header{"X-Checksum": {"abc"}},
+		},
+		{
+			MergeKeepExisting,
+			http.Header{"X-Name": {"old"}, "X-Tag": {"old"}, "X-Other": {"old"}},
+			http.Header{"X-Checksum": {"old"}},
+		},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header = http.Header{"X-Name": {"old"}, "X-Tag": {"old"}, "X-Other": {"old"}}
+		req.Trailer = http.Header{"X-Checksum": {"old"}}
+		if err := EncodeToRequest(req, s, tt.mode); err != nil {
+			t.Errorf("EncodeToRequest returned error: %v", err)
+		}
+		if !reflect.DeepEqual(req.Header, tt.want) {
+			t.Errorf("mode %d: Header = %v, want %v", tt.mode, req.Header, tt.want)
+		}
+		if !reflect.DeepEqual(req.Trailer, tt.wantTrailer) {
+			t.Errorf("mode %d: Trailer = %v, want %v", tt.mode, req.Trailer, tt.wantTrailer)
+		}
+	}
+
+	// nil Header and Trailer maps are allocated
+	resp := &http.Response{}
+	if err := EncodeToResponse(resp, &s, MergeSet); err != nil {
+		t.Errorf("EncodeToResponse returned error: %v", err)
+	}
+	want := http.Header{"X-Name": {"foo"}, "X-Tag": {"a", "b"}}
+	if !reflect.DeepEqual(resp.Header, want) {
+		t.Errorf("Header = %v, want %v", resp.Header, want)
+	}
+	if want := (http.Header{"X-Checksum": {"abc"}}); !reflect.DeepEqual(resp.Trailer, want) {
+		t.Errorf("Trailer = %v, want %v", resp.Trailer, want)
+	}
+
+	if err := EncodeToRequest(httptest.NewRequest("GET", "/", nil), 1, MergeAdd); err == nil {
+		t.Error("expected EncodeToRequest to return an error for non-struct values")
+	}
+}
+
+func TestEncodeToResponseWriter(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Set("X-Name", "old")
+	s := mergeStruct{Name: "foo", Tags: []string{"a"}}
+	if err := EncodeToResponseWriter(w, s, MergeSet); err != nil {
+		t.Errorf("EncodeToResponseWriter returned error: %v", err)
+	}
+	w.WriteHeader(http.StatusOK)
+	s.Checksum = "abc"
+	if err := EncodeToResponseWriter(w, struct {
+		Checksum string `header:"X-Checksum,trailer"`
+	}{s.Checksum}, MergeAdd); err != nil {
+		t.Errorf("EncodeToResponseWriter returned error: %v", err)
+	}
+
+	resp := w.Result()
+	var got mergeStruct
+	if err := DecodeResponse(resp, &got); err != nil {
+		t.Errorf("DecodeResponse returned error: %v", err)
+	}
+	if !reflect.DeepEqual(got, s) {
+		t.Errorf("DecodeResponse got %#v, want %#v", got, s)
+	}
+}
+
+func TestDecodeRequest(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header = http.Header{"X-Name": {"foo"}, "X-Checksum": {"header"}}
+	var got mergeStruct
+	if err := DecodeRequest(r, &got); err != nil {
+		t.Errorf("DecodeRequest returned error: %v", err)
+	}
+	if want := (mergeStruct{Name: "foo"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeRequest got %#v, want %#v", got, want)
+	}
+
+	r.Trailer = http.Header{"X-Checksum": {"abc"}}
+	if err := DecodeRequest(r, &got); err != nil {
+		t.Errorf("DecodeRequest returned error: %v", err)
+	}
+	if want := (mergeStruct{Name: "foo", Checksum: "abc"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeRequest got %#v, want %#v", got, want)
+	}
+
+	if err := DecodeRequest(r, got); err == nil {
+		t.Error("expected DecodeRequest to return an error for non-pointer values")
+	}
+}
@@ -0,0 +1,132 @@
+package httpheader
+
+import (
+	"context"
+	"net/http"
+)
+
+type optionsKey struct{}
+
+type metadataKey struct{}
+
+// metadata is the struct decoded by a Transport from the Header of a
+// response, and the error decoding it.
+type metadata struct {
+	v   interface{}
+	err error
+}
+
+// ContextWithOptions returns a copy of ctx holding the struct v, which a
+// Transport encodes into the Header of the requests made with that context.
+func ContextWithOptions(ctx context.Context, v interface{}) context.Context {
+	return context.WithValue(ctx, optionsKey{}, v)
+}
+
+// ResponseMetadata returns the struct decoded by a Transport from the Header
+// of resp, see Transport.Metadata, or nil if there is none. It also returns
+// the error decoding the Header, in which case the struct may be partially
+// decoded.
+func ResponseMetadata(resp *http.Response) (interface{}, error) {
+	if resp.Request == nil {
+		return nil, nil
+	}
+	md, ok := resp.Request.Context().Value(metadataKey{}).(*metadata)
+	if !ok {
+		return nil, nil
+	}
+	return md.v, md.err
+}
+
+// Transport is an http.RoundTripper that encodes a struct into the Header of
+// each request, and decodes the Header of each response into a struct.
+type Transport struct {
+	// Base is the RoundTripper making the requests, http.DefaultTransport
+	// if nil.
+	Base http.RoundTripper
+
+	// Options returns the struct encoded into the Header of req, nil for
+	// none. If Options is nil, the struct stored in the request context by
+	// ContextWithOptions is encoded.
+	Options func(req *http.Request) interface{}
+
+	// Mode is the merge mode of the encoded Header fields, see MergeMode.
+	Mode MergeMode
+
+	// Metadata returns a pointer to a new struct that the Header of each
+	// response is decoded into. The struct, and the error decoding it, are
+	// then available with ResponseMetadata: RoundTrip doesn't fail on
+	// invalid response Header fields. If Metadata is nil, responses are not
+	// decoded.
+	Metadata func() interface{}
+
+	// Encoder and Decoder are the HeaderEncoder and HeaderDecoder used by
+	// the Transport, the ones used by Header and Decode if nil.
+	Encoder *HeaderEncoder
+	Decoder *HeaderDecoder
+}
+
+// RoundTrip implements the http.RoundTripper interface. req is not modified,
+// the struct is encoded into a copy of it.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var v interface{}
+	if t.Options != nil {
+		v = t.Options(req)
+	} else {
+		v = req.Context().Value(optionsKey{})
+	}
+	if v != nil {
+		req2 := req.WithContext(req.Context()) // shallow copy
+		req2.Header = cloneHeader(req.Header)
+		if req.Trailer != nil {
+			req2.Trailer = cloneHeader(req.Trailer)
+		}
+		if err := t.encoder().EncodeToRequest(req2, v, t.Mode); err != nil {
+			closeBody(req)
+			return nil, err
+		}
+		req = req2
+	}
+
+	resp, err := t.base().RoundTrip(req)
+	if err != nil || t.Metadata == nil {
+		return resp, err
+	}
+
+	md := &metadata{v: t.Metadata()}
+	md.err = t.decoder().decodePtr(resp.Header, nil, md.v)
+	r := req
+	if resp.Request != nil {
+		r = resp.Request
+	}
+	resp.Request = r.WithContext(context.WithValue(r.Context(), metadataKey{}, md))
+	return resp, nil
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+func (t *Transport) encoder() *HeaderEncoder {
+	if t.Encoder != nil {
+		return t.Encoder
+	}
+	return defaultEncoder
+}
+
+func (t *Transport) decoder() *HeaderDecoder {
+	if t.Decoder != nil {
+		return t.Decoder
+	}
+	return defaultDecoder
+}
+
+// closeBody closes the body of req, as a RoundTripper must do even on
+// errors.
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
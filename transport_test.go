@@ -0,0 +1,94 @@
+package httpheader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type clientOptions struct {
+	Token   string `header:"Authorization"`
+	TraceID string `header:"X-Trace-Id,omitempty"`
+}
+
+type responseMetadata struct {
+	RequestID string `header:"X-Request-Id"`
+	Remaining int    `header:"X-Ratelimit-Remaining"`
+}
+
+func TestTransport(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+		w.Header().Set("X-Request-Id", "r1")
+		w.Header().Set("X-Ratelimit-Remaining", "9")
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &Transport{
+		Options: func(req *http.Request) interface{} {
+			return clientOptions{Token: "Bearer t"}
+		},
+		Mode:     MergeKeepExisting,
+		Metadata: func() interface{} { return new(responseMetadata) },
+	}}
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	req.Header.Set("X-Trace-Id", "keep")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do returned error: %v", err)
+	}
+	resp.Body.Close()
+
+	if v := got.Get("Authorization"); v != "Bearer t" {
+		t.Errorf("Authorization = %q, want %q", v, "Bearer t")
+	}
+	if v := got.Get("X-Trace-Id"); v != "keep" {
+		t.Errorf("X-Trace-Id = %q, want %q", v, "keep")
+	}
+	if len(req.Header) != 1 {
+		t.Errorf("request Header modified: %v", req.Header)
+	}
+	want := &responseMetadata{RequestID: "r1", Remaining: 9}
+	if md, err := ResponseMetadata(resp); err != nil || !reflect.DeepEqual(md, want) {
+		t.Errorf("ResponseMetadata returned %#v, %v, want %#v", md, err, want)
+	}
+}
+
+func TestTransport_context(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+		w.Header().Set("X-Ratelimit-Remaining", "a")
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &Transport{}}
+	ctx := ContextWithOptions(context.Background(), &clientOptions{Token: "t", TraceID: "1"})
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		t.Fatalf("Do returned error: %v", err)
+	}
+	resp.Body.Close()
+	if got.Get("Authorization") != "t" || got.Get("X-Trace-Id") != "1" {
+		t.Errorf("request Header = %v", got)
+	}
+	if md, err := ResponseMetadata(resp); md != nil || err != nil {
+		t.Errorf("ResponseMetadata returned %#v, %v, want nil", md, err)
+	}
+
+	// invalid response metadata doesn't fail the request
+	client.Transport = &Transport{Metadata: func() interface{} { return new(responseMetadata) }}
+	resp, err = client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+	resp.Body.Close()
+	md, err := ResponseMetadata(resp)
+	if _, ok := err.(*DecodeError); !ok || md == nil {
+		t.Errorf("ResponseMetadata returned %#v, %v, want *DecodeError", md, err)
+	}
+}
@@ -0,0 +1,126 @@
+package httpheader
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SanitizeMode selects how a HeaderEncoder handles Header field names and
+// values that are not valid, see WithSanitize.
+type SanitizeMode int
+
+const (
+	// SanitizeReject reports invalid names and values as an
+	// *InvalidHeaderError. This is the default.
+	SanitizeReject SanitizeMode = iota
+	// SanitizeStrip removes the invalid bytes.
+	SanitizeStrip
+	// SanitizeEncode replaces the invalid bytes by their percent-encoding,
+	// e.g. "%0D%0A" for "\r\n".
+	SanitizeEncode
+)
+
+// InvalidHeaderError is returned by Header when a struct field encodes to a
+// Header field name that is not a token, or to a Header field value holding
+// control characters, as defined by RFC 9110 section 5. Such fields could be
+// used to inject Header fields or split the HTTP message.
+type InvalidHeaderError struct {
+	Struct string // name of the struct type passed to Header
+	Field  string // path of the struct field, e.g. "Outer.Inner.Retry"
+	Header string // Header field name
+	Value  string // invalid Header field value, empty if Header is invalid
+}
+
+func (e *InvalidHeaderError) Error() string {
+	field := e.Field
+	if e.Struct != "" {
+		field = e.Struct + "." + field
+	}
+	if !validHeaderName(e.Header) {
+		return fmt.Sprintf("httpheader: invalid header name %q for field %s", e.Header, field)
+	}
+	return fmt.Sprintf("httpheader: invalid header %s value %q for field %s", e.Header, e.Value, field)
+}
+
+// InvalidListElementError is returned by Header when an element of a slice
+// or array field with the "comma" or "sep" option wouldn't decode back as is
+// from the joined Header value, as split by Decode.
+type InvalidListElementError struct {
+	Struct  string // name of the struct type passed to Header
+	Field   string // path of the struct field, e.g. "Outer.Inner.Tags"
+	Header  string // Header field name
+	Element string // invalid list element
+}
+
+func (e *InvalidListElementError) Error() string {
+	field := e.Field
+	if e.Struct != "" {
+		field = e.Struct + "." + field
+	}
+	return fmt.Sprintf("httpheader: invalid header %s list element %q for field %s", e.Header, e.Element, field)
+}
+
+// validHeaderName reports whether s is a token, see RFC 9110 section 5.6.2.
+func validHeaderName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isTokenChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// validHeaderValue reports whether s holds no control characters other than
+// horizontal tab, see RFC 9110 section 5.5.
+func validHeaderValue(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isValueChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// sanitizeHeaderName returns s without the bytes that are not allowed in a
+// token, or with those bytes percent-encoded, according to mode.
+func sanitizeHeaderName(s string, mode SanitizeMode) string {
+	return sanitize(s, mode, isTokenChar)
+}
+
+// sanitizeHeaderValue returns s without the bytes that are not allowed in a
+// Header field value, or with those bytes percent-encoded, according to mode.
+func sanitizeHeaderValue(s string, mode SanitizeMode) string {
+	return sanitize(s, mode, isValueChar)
+}
+
+func sanitize(s string, mode SanitizeMode, valid func(byte) bool) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case valid(c):
+			b.WriteByte(c)
+		case mode == SanitizeEncode:
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&0xf])
+		}
+	}
+	return b.String()
+}
+
+func isTokenChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0
+}
+
+func isValueChar(c byte) bool {
+	return c == '\t' || (c >= ' ' && c != 0x7f)
+}
@@ -0,0 +1,109 @@
+package httpheader
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+type injectEncoder string
+
+func (e injectEncoder) EncodeHeader(key string, h *http.Header) error {
+	h.Add(key, string(e))
+	return nil
+}
+
+func TestHeader_invalid(t *testing.T) {
+	type Inner struct {
+		Value string `header:"X-Value"`
+	}
+	type invalidStruct struct {
+		Inner Inner
+	}
+
+	tests := []struct {
+		in   interface{}
+		want *InvalidHeaderError
+	}{
+		{
+			invalidStruct{Inner{"a\r\nSet-Cookie: x=1"}},
+			&InvalidHeaderError{Struct: "invalidStruct", Field: "Inner.Value", Header: "X-Value", Value: "a\r\nSet-Cookie: x=1"},
+		},
+		{
+			struct {
+				Name string `header:"X Name"`
+			}{"a"},
+			&InvalidHeaderError{Field: "Name", Header: "X Name"},
+		},
+		{
+			struct {
+				Values []string `header:"X-Values"`
+			}{[]string{"a", "b\n"}},
+			&InvalidHeaderError{Field: "Values", Header: "X-Values", Value: "b\n"},
+		},
+		{
+			struct {
+				Meta map[string]string `header:"X-Meta-,prefix"`
+			}{map[string]string{"a:b": "c"}},
+			&InvalidHeaderError{Field: "Meta", Header: "X-Meta-a:b"},
+		},
+		{
+			struct {
+				H http.Header
+			}{http.Header{"X-H": []string{"\x00"}}},
+			&InvalidHeaderError{Field: "H", Header: "X-H", Value: "\x00"},
+		},
+		{
+			struct {
+				E injectEncoder `header:"X-E"`
+			}{"\r\n"},
+			&InvalidHeaderError{Field: "E", Header: "X-E", Value: "\r\n"},
+		},
+	}
+	for i, tt := range tests {
+		_, err := Header(tt.in)
+		if !reflect.DeepEqual(err, tt.want) {
+			t.Errorf("%d. Header(%#v) returned error %#v, want %#v", i, tt.in, err, tt.want)
+		}
+	}
+
+	s := struct {
+		Value string `header:"X-Value"`
+	}{"a\tb \x80"}
+	if _, err := Header(s); err != nil {
+		t.Errorf("Header(%#v) returned error: %v", s, err)
+	}
+}
+
+func TestHeaderEncoder_sanitize(t *testing.T) {
+	s := struct {
+		Value string            `header:"X-Value"`
+		Meta  map[string]string `header:"X-Meta-,prefix"`
+	}{"a\r\nb\x7f", map[string]string{"a b": "c"}}
+
+	tests := []struct {
+		mode SanitizeMode
+		want http.Header
+	}{
+		{SanitizeStrip, http.Header{"X-Value": []string{"ab"}, "X-Meta-Ab": []string{"c"}}},
+		{SanitizeEncode, http.Header{"X-Value": []string{"a%0D%0Ab%7F"}, "X-Meta-A%20b": []string{"c"}}},
+	}
+	for _, tt := range tests {
+		got, err := NewEncoder(WithSanitize(tt.mode)).Encode(s)
+		if err != nil {
+			t.Errorf("Encode returned error: %v", err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Encode returned %v, want %v", got, tt.want)
+		}
+	}
+
+	// names without any valid byte can't be sanitized
+	empty := struct {
+		Name string `header:"\n"`
+	}{"a"}
+	_, err := NewEncoder(WithSanitize(SanitizeStrip)).Encode(empty)
+	if want := (&InvalidHeaderError{Field: "Name", Header: "\n"}); !reflect.DeepEqual(err, want) {
+		t.Errorf("Encode returned error %#v, want %#v", err, want)
+	}
+}